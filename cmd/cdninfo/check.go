@@ -1,10 +1,19 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"github.com/winezer0/cdninfo/internal/checkpoint"
 	"github.com/winezer0/cdninfo/internal/config"
+	"github.com/winezer0/cdninfo/internal/pipeline"
 	"github.com/winezer0/cdninfo/pkg/fileutils"
+	"github.com/winezer0/cdninfo/pkg/inputs"
+	"github.com/winezer0/xutils/logging"
+	"io"
+	"os"
 	"strings"
+	"time"
 )
 
 // 使用命令行非默认值参数更新配置文件中的参数
@@ -43,45 +52,199 @@ func updateAppConfigByOpts(appConfig *config.AppConfig, cmdConfig *Options) *con
 	return appConfig
 }
 
-func parseTargetFomat(target string, targetType string) ([]string, error) {
-	var (
-		targets []string
-		err     error
-	)
-
+// parseTargetFomat 打开目标输入并以流的形式逐个输出，读取完成后关闭通道
+// targetType 为 str/file/sys 时按行读取, 为 csv:column、jsonl:field、nmap 等格式时从文件读取, 文件为空或 - 时读取 stdin
+// 文件支持逗号分隔的多个路径和通配符, gzip/zstd 压缩的文件自动解压
+func parseTargetFomat(target string, targetType string) (<-chan inputs.Target, error) {
 	if target == "" && (targetType == "str" || targetType == "file") {
 		return nil, fmt.Errorf("the target must be specified")
 	}
 
-	switch targetType {
-	case "str":
-		targets = strings.Split(target, ",")
-	case "file":
-		targets, err = fileutils.ReadTextToList(target)
-		if err != nil {
+	format := "lines"
+	var files []string
+	var err error
+	switch {
+	case targetType == "str":
+	case targetType == "file":
+		if files, err = fileutils.ExpandInputFiles(target); err != nil {
 			return nil, fmt.Errorf("failed to load the target file: %v", err)
 		}
-	case "sys":
-		targets, err = fileutils.ReadPipeToList()
+	case targetType == "sys":
+	case inputs.IsFormat(targetType):
+		format = targetType
+		if target != "" && target != "-" {
+			if files, err = fileutils.ExpandInputFiles(target); err != nil {
+				return nil, fmt.Errorf("failed to load the target file: %v", err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported target type: %s (support: str/file/sys/%s)", targetType, strings.Join(inputs.Formats(), "/"))
+	}
+
+	targets := make(chan inputs.Target, 1024)
+	go func() {
+		defer close(targets)
+
+		if targetType == "str" {
+			inputs.ReadList(target, targets)
+			return
+		}
+
+		// 未指定文件时从 stdin 读取
+		if len(files) == 0 {
+			readTargets(format, "stdin", io.NopCloser(os.Stdin), targets)
+			return
+		}
+
+		// 逐个打开文件读取, 避免同时打开大量文件
+		for _, filePath := range files {
+			file, err := os.Open(filePath)
+			if err != nil {
+				logging.Errorf("failed to open the target file [%s]: %v", filePath, err)
+				continue
+			}
+			readTargets(format, filePath, file, targets)
+		}
+	}()
+	return targets, nil
+}
+
+// waitFirstTarget 等待输入中的第一个目标, 没有任何目标时返回 false
+// 返回的通道先输出第一个目标再转发其余目标, 用于在创建输出文件之前检查输入是否为空
+func waitFirstTarget(targets <-chan inputs.Target) (<-chan inputs.Target, bool) {
+	first, ok := <-targets
+	if !ok {
+		return nil, false
+	}
+	out := make(chan inputs.Target, cap(targets))
+	go func() {
+		defer close(out)
+		out <- first
+		for target := range targets {
+			out <- target
+		}
+	}()
+	return out, true
+}
+
+// readTargets 按输入格式读取单个输入源中的目标并在读取后关闭
+func readTargets(format, name string, source io.ReadCloser, targets chan<- inputs.Target) {
+	reader, err := fileutils.NewDecompressReader(source)
+	if err != nil {
+		source.Close()
+		logging.Errorf("failed to read the targets [%s]: %v", name, err)
+		return
+	}
+	defer reader.Close()
+
+	count, err := inputs.Read(format, reader, targets)
+	if err != nil {
+		logging.Errorf("failed to read the targets [%s]: %v", name, err)
+	}
+	logging.Debugf("Read %d targets from [%s]", count, name)
+}
+
+// openResumeState 打开断点续扫状态文件，记录输入指纹和输出设置用于恢复时检查
+func openResumeState(opts *Options) (*checkpoint.State, error) {
+	inputType := strings.ToLower(opts.InputType)
+	header := checkpoint.Header{
+		InputType:   inputType,
+		Input:       opts.Input,
+		OutputType:  strings.ToLower(opts.OutputType),
+		Output:      opts.Output,
+		OutputLevel: opts.OutputLevel,
+	}
+	if len(opts.Outs) > 0 {
+		header.OutputType, header.Output = checkpoint.OutputMulti, strings.Join(opts.Outs, ",")
+	}
+
+	switch {
+	case inputType == "file" || (inputs.IsFormat(inputType) && opts.Input != "" && opts.Input != "-"):
+		fingerprint, err := hashInputFiles(opts.Input)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load the system pipe: %v", err)
+			return nil, fmt.Errorf("failed to hash the target file: %v", err)
 		}
+		header.InputFingerprint = fingerprint
+	case inputType == "str":
+		sum := sha256.Sum256([]byte(opts.Input))
+		header.InputFingerprint = hex.EncodeToString(sum[:])
 	default:
-		return nil, fmt.Errorf("unsupported target type: %s", targetType)
+		logging.Warnf("The input from [%s] can not be verified when resuming", inputType)
+	}
+
+	state, err := checkpoint.Open(opts.ResumeFile, header)
+	if err != nil {
+		return nil, err
+	}
+	if state.Resumed {
+		logging.Infof("Resume from [%s], %d targets already done", opts.ResumeFile, state.DoneCount())
 	}
+	return state, nil
+}
+
+// 断点续扫时批量记录完成状态的条件, 满足任一条件时持久化输出后记录
+const (
+	resumeSyncSize     = 500
+	resumeSyncInterval = 5 * time.Second
+)
 
-	// 过滤空字符串
-	filtered := make([]string, 0, len(targets))
-	for _, t := range targets {
-		t = strings.TrimSpace(t)
-		if t != "" {
-			filtered = append(filtered, t)
+// resumeMarker 暂存已写入输出的结果, 每 resumeSyncSize 条或间隔 resumeSyncInterval 持久化一次输出后一起记录完成状态
+// 各输出器的批次和事务因此照常生效, 进程被强制结束时最多重复输出未记录的一组结果
+type resumeMarker struct {
+	writer   fileutils.SyncWriter
+	state    *checkpoint.State
+	pending  []*pipeline.Record
+	lastSync time.Time
+}
+
+func newResumeMarker(writer fileutils.SyncWriter, state *checkpoint.State) *resumeMarker {
+	return &resumeMarker{writer: writer, state: state, lastSync: time.Now()}
+}
+
+// add 暂存一条已写入输出的结果, 满足条件时调用 flush
+func (m *resumeMarker) add(record *pipeline.Record) error {
+	m.pending = append(m.pending, record)
+	if len(m.pending) >= resumeSyncSize || time.Since(m.lastSync) >= resumeSyncInterval {
+		return m.flush()
+	}
+	return nil
+}
+
+// flush 持久化输出后记录暂存结果的完成状态, 持久化失败时不记录
+func (m *resumeMarker) flush() error {
+	m.lastSync = time.Now()
+	if len(m.pending) == 0 {
+		return nil
+	}
+	if err := m.writer.Sync(); err != nil {
+		return err
+	}
+	for _, record := range m.pending {
+		if err := m.state.MarkDone(record.Info.RAW, record.Info.FMT, record.Result); err != nil {
+			return err
 		}
 	}
+	m.pending = m.pending[:0]
+	return nil
+}
 
-	if len(filtered) == 0 {
-		return nil, fmt.Errorf("no valid target has been entered")
+// hashInputFiles 计算所有输入文件的整体指纹, 文件列表或任一文件内容变化时指纹随之变化
+func hashInputFiles(patterns string) (string, error) {
+	files, err := fileutils.ExpandInputFiles(patterns)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 1 {
+		return fileutils.HashFile(files[0])
 	}
 
-	return filtered, nil
+	hash := sha256.New()
+	for _, file := range files {
+		fileHash, err := fileutils.HashFile(file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s  %s\n", fileHash, file)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
 }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+	"github.com/winezer0/cdninfo/internal/checkpoint"
+	"github.com/winezer0/cdninfo/internal/pipeline"
+	"github.com/winezer0/cdninfo/pkg/inputs"
+)
+
+func TestWaitFirstTarget(t *testing.T) {
+	empty := make(chan inputs.Target)
+	close(empty)
+	if _, ok := waitFirstTarget(empty); ok {
+		t.Fatal("expected no target")
+	}
+
+	targets := make(chan inputs.Target, 3)
+	for _, value := range []string{"a.com", "b.com", "c.com"} {
+		targets <- inputs.Target{Value: value}
+	}
+	close(targets)
+	out, ok := waitFirstTarget(targets)
+	if !ok {
+		t.Fatal("expected targets")
+	}
+	var got []string
+	for target := range out {
+		got = append(got, target.Value)
+	}
+	if len(got) != 3 || got[0] != "a.com" || got[2] != "c.com" {
+		t.Fatalf("unexpected targets: %v", got)
+	}
+}
+
+// syncCounter 记录 Sync 调用次数, fail 为 true 时返回错误
+type syncCounter struct {
+	syncs int
+	fail  bool
+}
+
+func (s *syncCounter) Sync() error {
+	s.syncs++
+	if s.fail {
+		return errors.New("webhook down")
+	}
+	return nil
+}
+
+func TestResumeMarker(t *testing.T) {
+	state, err := checkpoint.Open(filepath.Join(t.TempDir(), "state.jsonl"), checkpoint.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer state.Close()
+
+	writer := &syncCounter{}
+	marker := newResumeMarker(writer, state)
+	record := func(i int) *pipeline.Record {
+		value := fmt.Sprintf("%d.example.com", i)
+		return &pipeline.Record{Info: &analyzer.CheckInfo{RAW: value, FMT: value}}
+	}
+
+	// 满一组后才持久化并记录完成状态
+	for i := 0; i < resumeSyncSize; i++ {
+		if err := marker.add(record(i)); err != nil {
+			t.Fatal(err)
+		}
+		if i < resumeSyncSize-1 && (writer.syncs != 0 || state.DoneCount() != 0) {
+			t.Fatalf("record %d: syncs %d done %d before the group is full", i, writer.syncs, state.DoneCount())
+		}
+	}
+	if writer.syncs != 1 || state.DoneCount() != resumeSyncSize {
+		t.Fatalf("syncs %d done %d after a full group", writer.syncs, state.DoneCount())
+	}
+
+	// 持久化失败时不记录完成状态
+	writer.fail = true
+	_ = marker.add(record(resumeSyncSize))
+	if err := marker.flush(); err == nil || state.DoneCount() != resumeSyncSize {
+		t.Fatalf("flush error %v, done %d after a failed sync", err, state.DoneCount())
+	}
+	writer.fail = false
+	if err := marker.flush(); err != nil || state.DoneCount() != resumeSyncSize+1 {
+		t.Fatalf("flush error %v, done %d", err, state.DoneCount())
+	}
+	// 没有暂存结果时不再持久化
+	syncs := writer.syncs
+	if err := marker.flush(); err != nil || writer.syncs != syncs {
+		t.Fatalf("flush error %v, syncs %d without pending records", err, writer.syncs)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/winezer0/cdninfo/internal/diff"
+	"github.com/winezer0/cdninfo/pkg/maputils"
+)
+
+// DiffOptions diff 子命令参数
+type DiffOptions struct {
+	Format   string `long:"format" description:"diff output format: text/json/jsonl" default:"text" choice:"text" choice:"json" choice:"jsonl"`
+	Types    string `long:"types" description:"only report these change types (separated by commas), such as cdn_removed,ips_added" default:""`
+	ExitCode bool   `long:"exit-code" description:"exit with status 1 when there are changes"`
+
+	Args struct {
+		Old string `positional-arg-name:"old" description:"old scan: json/jsonl result file or sqlite database (history.db#scan_id)"`
+		New string `positional-arg-name:"new" description:"new scan: json/jsonl result file or sqlite database (history.db#scan_id)"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// runDiff 对比两次扫描的结果, 返回进程退出码: 0 无变化或未指定 --exit-code, 1 有变化, 2 出错
+func runDiff(args []string) int {
+	opts := &DiffOptions{}
+	parser := flags.NewParser(opts, flags.Default)
+	parser.Name = AppName + " diff"
+	parser.Usage = "[OPTIONS] old new"
+	if _, err := parser.ParseArgs(args); err != nil {
+		var flagsErr *flags.Error
+		if errors.As(err, &flagsErr) && errors.Is(flagsErr.Type, flags.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	types := maputils.StripStrings(strings.Split(strings.ToLower(opts.Types), ","))
+	for _, t := range types {
+		if !diff.IsChangeType(t) {
+			fmt.Fprintf(os.Stderr, "Error: unknown change type [%s], supported types: %s\n", t, strings.Join(diff.ChangeTypes, ","))
+			return 2
+		}
+	}
+
+	oldInfos, err := diff.LoadResults(opts.Args.Old)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: load old scan [%s] failed: %v\n", opts.Args.Old, err)
+		return 2
+	}
+	newInfos, err := diff.LoadResults(opts.Args.New)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: load new scan [%s] failed: %v\n", opts.Args.New, err)
+		return 2
+	}
+
+	changes := diff.Compare(oldInfos, newInfos)
+	if len(types) > 0 {
+		changes = filterChanges(changes, types)
+	}
+
+	if err := writeChanges(os.Stdout, opts.Format, changes); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: write diff failed: %v\n", err)
+		return 2
+	}
+	if opts.ExitCode && len(changes) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func filterChanges(changes []diff.Change, types []string) []diff.Change {
+	var result []diff.Change
+	for _, change := range changes {
+		for _, t := range types {
+			if strings.EqualFold(change.Type, t) {
+				result = append(result, change)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func writeChanges(w io.Writer, format string, changes []diff.Change) error {
+	switch format {
+	case "json":
+		if changes == nil {
+			changes = []diff.Change{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(changes)
+	case "jsonl":
+		encoder := json.NewEncoder(w)
+		for _, change := range changes {
+			if err := encoder.Encode(change); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		for _, change := range changes {
+			if _, err := fmt.Fprintln(w, change.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -3,25 +3,44 @@ package main
 import (
 	"github.com/winezer0/cdninfo/internal/config"
 	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/winezer0/ipinfo/pkg/queryip"
 
 	"github.com/winezer0/cdninfo/internal/analyzer"
-	"github.com/winezer0/cdninfo/internal/docheck"
+	"github.com/winezer0/cdninfo/internal/checkpoint"
+	"github.com/winezer0/cdninfo/internal/pipeline"
 	"github.com/winezer0/cdninfo/pkg/classify"
 	"github.com/winezer0/cdninfo/pkg/domaininfo/querydomain"
 	"github.com/winezer0/cdninfo/pkg/fileutils"
+	"github.com/winezer0/cdninfo/pkg/progress"
 	"github.com/winezer0/xutils/logging"
 )
 
 func main() {
+	// 子命令: 对比两次扫描的结果
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		os.Exit(runDiff(os.Args[2:]))
+	}
+
 	// 定义命令行参数
 	// 打印命令行输入配置
 	opts, _ := InitOptionsArgs(0)
 	defer logging.Sync()
 
+	// 解析结果过滤表达式, 在加载数据前检查语法
+	var filter *analyzer.Filter
+	if opts.Filter != "" {
+		var err error
+		if filter, err = analyzer.ParseFilter(opts.Filter); err != nil {
+			logging.Fatalf("Failed to parse filter: %v\n", err)
+		}
+	}
+
 	// 加载配置文件远程更新
 	var appConfig = &config.AppConfig{}
 	appConfig, err := config.LoadConfig(opts.ConfigFile)
@@ -48,9 +67,6 @@ func main() {
 	//使用cmd配置更新配置文件中的某些配置
 	appConfig = updateAppConfigByOpts(appConfig, opts)
 
-	// 分类输入数据为 IP Domain InvalidEntries
-	classifier := classify.ClassifyTargets(targets)
-
 	//加载dns解析服务器配置文件，用于dns解析调用
 	resolvers, err := config.LoadResolvers(dbPathsInfo.ResolversFile, appConfig.ResolversNum)
 	if err != nil {
@@ -67,6 +83,9 @@ func main() {
 		logging.Debugf("Success get rand cities: %v", randCities)
 	}
 
+	// 运行统计, 由各查询模块通过配置记录
+	stats := progress.NewStats()
+
 	// 配置DNS查询参数
 	dnsConfig := &querydomain.DNSQueryConfig{
 		Resolvers:          resolvers,
@@ -77,15 +96,7 @@ func main() {
 		QueryEDNSCNAMES:    appConfig.QueryEDNSCNAMES,
 		QueryEDNSUseSysNS:  appConfig.QueryEDNSUseSysNS,
 		QueryType:          appConfig.QueryMethod, // 添加查询类型配置
-	}
-
-	// 进行DNS解析
-	checkInfos := docheck.QueryDomainInfo(dnsConfig, classifier.DomainEntries)
-
-	//将所有IP信息加入到 checkInfos 中
-	for _, ipEntries := range classifier.IPEntries {
-		checkInfo := analyzer.NewIPCheckInfo(ipEntries.RAW, ipEntries.FMT, ipEntries.IsIPv4, ipEntries.FromUrl)
-		checkInfos = append(checkInfos, checkInfo)
+		Stats:              stats,
 	}
 
 	//对 checkInfos 中的IP数据进行分析
@@ -95,8 +106,6 @@ func main() {
 		Ipv6LocateDb: dbPathsInfo.Ipv6LocateDb,
 	}
 
-	checkInfos = docheck.QueryIPInfo(ipDbConfig, checkInfos)
-
 	// 加载sources.json配置文件
 	if _, err := os.Stat(dbPathsInfo.CdnSource); os.IsNotExist(err) {
 		logging.Fatalf("Error: The CDN source data not exist.: %s\n", dbPathsInfo.CdnSource)
@@ -110,35 +119,134 @@ func main() {
 		logging.Debugf("Success load CDN source data: %v", dbPathsInfo.CdnSource)
 	}
 
-	//进行CDN CLOUD WAF 信息分析
-	checkResults, err := analyzer.CheckCDNBatch(cdnData, checkInfos)
-	if err != nil {
-		logging.Fatalf("Failed to analysis CDN info: %v\n", err)
-	} else {
-		logging.Debugf("Success analysis CDN info: %v", dbPathsInfo.CdnSource)
+	// 没有任何目标时在创建断点状态和输出之前退出, 避免覆盖已有的结果文件
+	targets, ok := waitFirstTarget(targets)
+	if !ok {
+		logging.Fatalf("target [%s] loading failed: no valid target has been entered\n", opts.Input)
 	}
 
-	//排除Cdn|WAF部分的结果
-	if opts.OutputNoCDN {
-		checkResults = analyzer.FilterNoCdnNoWaf(checkResults)
+	// 加载断点续扫状态, 恢复时跳过已完成目标并追加写入已有结果文件
+	var state *checkpoint.State
+	if opts.ResumeFile != "" {
+		if err = checkResumeOutputs(opts); err != nil {
+			logging.Fatalf("Failed to resume: %v\n", err)
+		}
+		state, err = openResumeState(opts)
+		if err != nil {
+			logging.Fatalf("Failed to open resume state [%s]: %v\n", opts.ResumeFile, err)
+		}
+	}
+
+	// 创建流式输出器, 使用 --out 时同一次扫描写入多个输出
+	writer, err := openOutputs(opts, state != nil && state.Resumed)
+	if err != nil {
+		logging.Fatalf("Failed to create output writer: %v\n", err)
 	}
+	// 断点续扫时任一输出失败都停止运行, 未写入的目标不会被记录为完成, 恢复时重新处理
+	writer.FailFast = state != nil
+
+	// 中断时等待当前结果写完后关闭输出器和断点状态并输出统计, 使结果文件保持完整, 可以通过 --resume 继续
+	var emitMu sync.Mutex
+	var marker *resumeMarker
+	if state != nil {
+		marker = newResumeMarker(writer, state)
+	}
+	closeOutputs := sync.OnceFunc(func() {
+		if marker != nil {
+			if err := marker.flush(); err != nil {
+				logging.Errorf("Record done targets occur error: %v", err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			logging.Errorf("Write analysis results occur error: %v", err)
+		}
+		if state != nil {
+			if err := state.Close(); err != nil {
+				logging.Errorf("Close resume state occur error: %v", err)
+			}
+		}
+	})
+
+	// 分类 -> DNS解析 -> IP信息查询 -> CDN CLOUD WAF 信息分析 -> 输出, 各阶段通过有界通道连接
+	pipeConfig := &pipeline.Config{
+		DNSConfig:      dnsConfig,
+		IPDbConfig:     ipDbConfig,
+		CDNData:        cdnData,
+		BatchSize:      opts.BatchSize,
+		ResolveWorkers: opts.ResolveWorkers,
+		RangeMode:      opts.RangeMode,
+		RangeMaxSize:   opts.RangeMaxSize,
+		NoDedupe:       opts.NoDedupe,
+		DedupeCache:    opts.DedupeCache,
+		Stats:          stats,
+	}
+	if state != nil {
+		pipeConfig.Skip = func(entry classify.TargetEntry) bool { return state.IsDone(entry.RAW, entry.FMT) }
+	}
+	pipe := pipeline.NewPipeline(pipeConfig)
 
-	// 处理输出详细程度
-	var outputData interface{}
-	switch opts.OutputLevel {
-	case 1:
-		// 仅输出fmt部分的内容
-		outputData = analyzer.GetFmtList(checkResults)
-	case 3:
-		// 合并 checkResults 到 checkInfos
-		outputData = analyzer.MergeCheckResultsToCheckInfos(checkInfos, checkResults)
-	default:
-		// 输出 checkResults
-		outputData = checkResults
+	// 在 stderr 上刷新运行状态, 不影响 stdout 上的结果输出
+	var reporter *progress.Reporter
+	if opts.Progress {
+		reporter = progress.StartReporter(stats, os.Stderr, time.Second)
 	}
 
-	// 处理输出类型
-	if err = fileutils.WriteOutputToFile(outputData, opts.OutputType, opts.Output); err != nil {
-		logging.Debugf("Write analysis results to [%v] occur error: %v", opts.Output, err)
+	// 输出运行统计, 中断时同样输出已完成部分的统计
+	reportStats := sync.OnceFunc(func() {
+		if reporter != nil {
+			reporter.Stop()
+			pipe.Classifier().ShowSummary(os.Stderr)
+			progress.WriteSummary(os.Stderr, stats.Snapshot())
+		}
+		if opts.StatsFile != "" {
+			if err := progress.WriteStatsFile(opts.StatsFile, stats.Snapshot()); err != nil {
+				logging.Errorf("Write statistics to [%v] occur error: %v", opts.StatsFile, err)
+			}
+		}
+	})
+	handleInterrupt(func() {
+		emitMu.Lock()
+		closeOutputs()
+		reportStats()
+	})
+
+	err = pipe.Run(targets, func(record *pipeline.Record) error {
+		emitMu.Lock()
+		defer emitMu.Unlock()
+
+		//排除Cdn|WAF部分的结果, 再按过滤表达式筛选, 各输出器按输出详细程度构造输出条目
+		if (!opts.OutputNoCDN || analyzer.IsNoCdnNoWaf(record.Result)) &&
+			(filter == nil || filter.MatchResult(record.Info, record.Result)) {
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		// 结果写入后暂存, 批量持久化输出后再记录完成状态
+		if marker != nil {
+			return marker.add(record)
+		}
+		return nil
+	})
+	pipeErr := err
+
+	closeOutputs()
+	reportStats()
+	// 输出已关闭、统计已写入后再以非零状态退出
+	if pipeErr != nil {
+		logging.Fatalf("Analysis pipeline occur error: %v", pipeErr)
 	}
 }
+
+// handleInterrupt 收到 SIGINT/SIGTERM 时执行 cleanup 后退出, cleanup 期间再次收到信号时直接退出
+func handleInterrupt(cleanup func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		signal.Stop(signals)
+		logging.Warnf("Received %v, closing outputs before exit", sig)
+		cleanup()
+		logging.Sync()
+		os.Exit(130)
+	}()
+}
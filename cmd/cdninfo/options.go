@@ -26,13 +26,48 @@ type Options struct {
 
 	// 基本参数 覆盖app Config中的配置
 	Input     string `short:"i" long:"input" description:"input file or str list (separated by commas)"`
-	InputType string `short:"I" long:"input-type" description:"input data type: str/file/sys (default str)" default:"str" choice:"file" choice:"str" choice:"sys"`
+	InputType string `short:"I" long:"input-type" description:"input data type: str/file/sys, or input file format: csv[:column]/jsonl[:field]/nmap/masscan/har/burp (default str)" default:"str"`
+
+	// CIDR/IP区间处理
+	RangeMode    string `long:"range-mode" description:"cidr/ip range handling: expand to single ips or analyze as a block" default:"expand" choice:"expand" choice:"block"`
+	RangeMaxSize int    `long:"range-max" description:"max ips expanded from one range, larger ranges are analyzed as a block" default:"65536"`
 
 	// 输出配置参数 覆盖app Config中的配置
-	Output      string `short:"o" long:"output" description:"output file path (default result.json)" default:"result.json"`
-	OutputType  string `short:"O" long:"output-type" description:"output file type: csv/json/txt/sys (default sys)" default:"sys" choice:"csv" choice:"json" choice:"txt" choice:"sys"`
-	OutputLevel int    `short:"l" long:"output-level" description:"Output verbosity level: 1=quiet, 2=default, 3=detail (default 2)" default:"2" choice:"1" choice:"2" choice:"3"`
-	OutputNoCDN bool   `short:"n" long:"output-no-cdn" description:"only output Info where not CDN and not WAF."`
+	Output         string `short:"o" long:"output" description:"output file path, or the url for webhook output (default result.json)" default:"result.json"`
+	OutputType     string `short:"O" long:"output-type" description:"output file type: csv/json/jsonl/txt/xlsx/html/sqlite/webhook/sys (default sys)" default:"sys" choice:"csv" choice:"json" choice:"jsonl" choice:"txt" choice:"xlsx" choice:"html" choice:"sqlite" choice:"webhook" choice:"sys"`
+	OutputLevel    int    `short:"l" long:"output-level" description:"Output verbosity level: 1=quiet, 2=default, 3=detail (default 2)" default:"2" choice:"1" choice:"2" choice:"3"`
+	OutputNoCDN    bool   `short:"n" long:"output-no-cdn" description:"only output Info where not CDN and not WAF."`
+	Filter         string `long:"filter" description:"only output results matching the expression, such as: is_cloud && cloud_company != \"aliyun\", ip_size > 5, cname ~ \"akamai\"" default:""`
+	OutputURL      bool   `long:"output-url" description:"output targets rebuilt as scheme://host:port for downstream scanners, instead of the level output"`
+	Include        string `long:"include" description:"field groups added to the output level (separated by commas): dns/geo/asn/verdict/evidence/timing" default:""`
+	Exclude        string `long:"exclude" description:"field groups removed from the output level (separated by commas): dns/geo/asn/verdict/evidence/timing" default:""`
+	Fields         string `long:"fields" description:"csv columns and order (separated by commas), such as raw,fmt,is_cdn,cdn_company,a (default all columns)" default:""`
+	ListSep        string `long:"list-sep" description:"separator joining list values in one csv cell" default:";"`
+	OutputTemplate string `long:"output-template" description:"go text/template file or inline string executed per result for txt/sys output, or a builtin: hosts/ips-only/markdown-table" default:""`
+	RealIPs        string `long:"real-ips" description:"export deduplicated non-CDN/WAF ips with their domains instead of the results: list/nmap/masscan/json" default:""`
+	RealIPCidr     bool   `long:"real-ip-cidr" description:"collapse the exported real ips into the fewest cidrs"`
+	GroupBy        string `long:"group-by" description:"aggregate the results by provider/asn/ip/registrable/subnet (ipv4 /24, ipv6 /64)" default:""`
+
+	// 多路输出, 同一次扫描写入多个输出
+	Outs []string `long:"out" description:"repeatable output type:path, such as json:result.json, csv:team.csv, real-ips-nmap:targets.txt, webhook:https://host/path, sys; -O/-o are ignored when set"`
+
+	// webhook 推送参数
+	WebhookSecret   string `long:"webhook-secret" env:"CDNINFO_WEBHOOK_SECRET" description:"sign webhook requests with HMAC-SHA256 in the X-Cdninfo-Signature header" default:""`
+	WebhookBatch    int    `long:"webhook-batch" description:"number of results sent per webhook request" default:"100"`
+	WebhookInterval int    `long:"webhook-interval" description:"send a partial webhook batch after waiting seconds, 0 only sends full batches and at exit" default:"5"`
+	WebhookRetries  int    `long:"webhook-retries" description:"retries of a webhook request on network errors, 429 and 5xx responses" default:"3"`
+	WebhookTimeout  int    `long:"webhook-timeout" description:"webhook request timeout in seconds" default:"30"`
+
+	// 流式处理参数
+	BatchSize      int    `long:"batch-size" description:"number of targets processed per pipeline batch" default:"100"`
+	ResolveWorkers int    `long:"resolve-workers" description:"number of batches resolved concurrently" default:"2"`
+	ResumeFile     string `long:"resume" description:"state file recording done targets, skip them and append to output on restart" default:""`
+	NoDedupe       bool   `long:"no-dedupe" description:"resolve targets with the same host separately instead of reusing the first result"`
+	DedupeCache    int    `long:"dedupe-cache" description:"max finished hosts whose result is kept for duplicates, least recently used are evicted" default:"100000"`
+
+	// 运行进度与统计
+	Progress  bool   `long:"progress" description:"show live status line on stderr and print statistics at exit"`
+	StatsFile string `long:"stats-file" description:"write run statistics to json file, such as stats.json" default:""`
 
 	// 数据库更新配置
 	ProxyUrl string `short:"p" long:"proxy" description:"use the proxy URL down files (support http|socks5)" default:""`
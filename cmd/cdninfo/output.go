@@ -0,0 +1,368 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+	"github.com/winezer0/cdninfo/internal/pipeline"
+	"github.com/winezer0/cdninfo/internal/report"
+	"github.com/winezer0/cdninfo/internal/store"
+	"github.com/winezer0/cdninfo/pkg/classify"
+	"github.com/winezer0/cdninfo/pkg/fileutils"
+	"github.com/winezer0/cdninfo/pkg/maputils"
+)
+
+// isReportType 报表类型在结束时根据全部完整结果一次性生成
+func isReportType(outputType string) bool {
+	return outputType == "xlsx" || outputType == "html"
+}
+
+// useFullInfo 报表、数据库、输出模板、真实 IP 导出和聚合输出需要包含DNS记录的完整结果, 忽略输出详细程度
+func useFullInfo(opts *Options) bool {
+	outputType := strings.ToLower(opts.OutputType)
+	return isReportType(outputType) || outputType == "sqlite" || opts.OutputTemplate != "" || opts.RealIPs != "" || opts.GroupBy != ""
+}
+
+// collectsResults 报表、真实 IP 导出和聚合输出在结束时根据本次运行收集的全部结果一次性写入
+func collectsResults(opts *Options) bool {
+	return isReportType(strings.ToLower(opts.OutputType)) || opts.RealIPs != "" || opts.GroupBy != ""
+}
+
+// checkResumeOutputs 断点续扫时跳过的目标不会再写入一次性生成的输出, 恢复后会丢失之前的结果, 因此不允许同时使用
+func checkResumeOutputs(opts *Options) error {
+	sinks := []*Options{opts}
+	if len(opts.Outs) > 0 {
+		var err error
+		if sinks, err = outputSinkOptions(opts); err != nil {
+			return err
+		}
+	}
+	for _, sink := range sinks {
+		if collectsResults(sink) {
+			return fmt.Errorf("--resume does not apply to xlsx/html reports, real ip export or --group-by output, got %s", sink.OutputType+":"+sink.Output)
+		}
+	}
+	return nil
+}
+
+// realIPsOutput --out 中真实 IP 导出的类型前缀, real-ips 为 list 格式, real-ips-<format> 指定格式
+const realIPsOutput = "real-ips"
+
+// outputSinkOptions 为每个 --out 配置生成对应的参数副本
+// 输出模板只用于 txt/sys, 聚合不用于 sqlite 和真实 IP 导出, 其他输出忽略这两个参数
+// 字段分组只用于 csv/json/jsonl/txt/sys 的单条结果, 使用完整结果的输出忽略 --include/--exclude
+func outputSinkOptions(opts *Options) ([]*Options, error) {
+	if opts.RealIPs != "" {
+		return nil, fmt.Errorf("--real-ips does not apply to --out, use --out real-ips-%s:path instead", opts.RealIPs)
+	}
+	var sinks []*Options
+	paths := make(map[string]bool)
+	for _, value := range opts.Outs {
+		spec, err := fileutils.ParseOutputSpec(value)
+		if err != nil {
+			return nil, err
+		}
+		sink := *opts
+		sink.Outs = nil
+		sink.OutputType, sink.Output, sink.RealIPs = spec.Type, spec.Path, ""
+		if format, ok := strings.CutPrefix(spec.Type, realIPsOutput); ok {
+			sink.RealIPs = strings.TrimPrefix(format, "-")
+			if sink.RealIPs == "" {
+				sink.RealIPs = "list"
+			}
+			if err := report.CheckRealIPFormat(sink.RealIPs); err != nil {
+				return nil, fmt.Errorf("invalid output spec %s: %w", value, err)
+			}
+			sink.OutputType = "txt"
+		} else if !isOutputType(spec.Type) {
+			return nil, fmt.Errorf("invalid output spec %s: unsupported type %s", value, spec.Type)
+		}
+
+		// 多个输出写入 stdout 或同一文件会互相覆盖
+		key := spec.Path
+		if spec.Type == "sys" {
+			key = "stdout"
+		}
+		if paths[key] {
+			return nil, fmt.Errorf("invalid output spec %s: %s is used by another output", value, key)
+		}
+		paths[key] = true
+
+		if (sink.OutputType != "txt" && sink.OutputType != "sys") || sink.RealIPs != "" {
+			sink.OutputTemplate = ""
+		}
+		if sink.OutputType == "sqlite" || sink.RealIPs != "" {
+			sink.GroupBy = ""
+		}
+		// 报表、数据库、输出模板、真实 IP 导出和聚合输出使用完整结果
+		if useFullInfo(&sink) {
+			sink.Include, sink.Exclude = "", ""
+		}
+		sinks = append(sinks, &sink)
+	}
+	return sinks, nil
+}
+
+// isOutputType 判断是否为 -O 支持的输出类型或已注册的消息队列类型
+func isOutputType(outputType string) bool {
+	switch outputType {
+	case "csv", "json", "jsonl", "txt", "xlsx", "html", "sqlite", "sys":
+		return true
+	}
+	return fileutils.IsRemoteOutput(outputType)
+}
+
+// isVersionedOutput jsonl 和推送到远程服务的每条结果都带有 schema_version 字段
+func isVersionedOutput(outputType string) bool {
+	return strings.ToLower(outputType) == "jsonl" || fileutils.IsRemoteOutput(outputType)
+}
+
+// openOutputs 创建全部输出器, 使用 --out 时为每个配置创建一个输出器, 否则使用 -O/-o
+// 写入的数据为 *pipeline.Record, 每个输出器按各自的参数构造输出条目
+func openOutputs(opts *Options, resumed bool) (*fileutils.MultiStreamWriter, error) {
+	sinks := []*Options{opts}
+	if len(opts.Outs) > 0 {
+		var err error
+		if sinks, err = outputSinkOptions(opts); err != nil {
+			return nil, err
+		}
+	}
+
+	multi := fileutils.NewMultiStreamWriter()
+	for _, sink := range sinks {
+		shape, err := newResultShape(sink)
+		if err == nil {
+			err = checkFields(sink, shape)
+		}
+		if err == nil {
+			var writer fileutils.StreamWriter
+			if writer, err = openOutputWriter(sink, resumed); err == nil {
+				sink := sink
+				multi.Add(sink.OutputType+":"+sink.Output, writer, func(item interface{}) interface{} {
+					return outputItem(sink, shape, item.(*pipeline.Record))
+				})
+				continue
+			}
+		}
+		multi.Close()
+		if len(opts.Outs) > 0 {
+			err = fmt.Errorf("%s: %w", sink.OutputType+":"+sink.Output, err)
+		}
+		return nil, err
+	}
+	return multi, nil
+}
+
+// outputItem 构造写入某个输出器的单条结果, 返回 nil 表示该条结果不输出
+// 报表、数据库、输出模板、真实 IP 导出和聚合输出始终使用完整结果
+func outputItem(opts *Options, shape *analyzer.ResultShape, record *pipeline.Record) interface{} {
+	if useFullInfo(opts) {
+		return analyzer.MergeCheckResultToCheckInfo(record.Info, record.Result)
+	}
+	if shape != nil {
+		return shape.Build(record.Info, record.Result)
+	}
+	item := buildOutputItem(opts.OutputLevel, opts.OutputURL, record)
+	if item != nil && useFieldsInfo(opts) {
+		// --fields 从完整结果中选择列, 各输出详细程度的列名保持一致
+		return analyzer.MergeCheckResultToCheckInfo(record.Info, record.Result)
+	}
+	if item != nil && isVersionedOutput(opts.OutputType) {
+		item = versionedItem(opts.OutputURL, item)
+	}
+	return item
+}
+
+// useFieldsInfo csv 输出指定 --fields 时从完整结果中选择列
+func useFieldsInfo(opts *Options) bool {
+	return opts.Fields != "" && strings.ToLower(opts.OutputType) == "csv" && !opts.OutputURL
+}
+
+// checkFields 检查 --fields 指定的列是否都存在, 列由输出条目的类型决定, 使用空结果构造样例即可
+func checkFields(opts *Options, shape *analyzer.ResultShape) error {
+	if opts.Fields == "" || strings.ToLower(opts.OutputType) != "csv" {
+		return nil
+	}
+	if opts.OutputURL {
+		return fmt.Errorf("--fields does not apply to --output-url")
+	}
+	var sample interface{} = report.Group{}
+	if opts.GroupBy == "" {
+		sample = outputItem(opts, shape, &pipeline.Record{Info: &analyzer.CheckInfo{}})
+	}
+	if err := fileutils.CheckColumns(sample, maputils.StripStrings(strings.Split(opts.Fields, ","))); err != nil {
+		return fmt.Errorf("invalid --fields: %w", err)
+	}
+	return nil
+}
+
+// openOutputWriter 根据输出类型创建输出器
+func openOutputWriter(opts *Options, resumed bool) (fileutils.StreamWriter, error) {
+	outputType := strings.ToLower(opts.OutputType)
+	if opts.GroupBy != "" {
+		if err := report.CheckGroupBy(opts.GroupBy); err != nil {
+			return nil, err
+		}
+		if opts.RealIPs != "" || outputType == "sqlite" {
+			return nil, fmt.Errorf("--group-by does not apply to --real-ips or sqlite output")
+		}
+	}
+	if opts.RealIPs != "" {
+		if err := report.CheckRealIPFormat(opts.RealIPs); err != nil {
+			return nil, err
+		}
+		// sys 输出到 stdout, 其他类型写入 -o 指定的文件
+		if outputType == "sys" {
+			return report.NewRealIPWriter(opts.RealIPs, opts.RealIPCidr, os.Stdout, nil)
+		}
+		file, err := os.Create(opts.Output)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open output file: %w", err)
+		}
+		return report.NewRealIPWriter(opts.RealIPs, opts.RealIPCidr, file, file)
+	}
+
+	switch outputType {
+	case "xlsx":
+		xlsxReport := report.NewXlsxReport(opts.Output, opts.ListSep)
+		xlsxReport.GroupBy = opts.GroupBy
+		return xlsxReport, nil
+	case "html":
+		htmlReport := report.NewHtmlReport(opts.Output)
+		htmlReport.GroupBy = opts.GroupBy
+		return htmlReport, nil
+	case "sqlite":
+		// 多次运行累积在同一个数据库中, 恢复时继续写入未完成的扫描
+		meta := store.ScanMeta{InputType: strings.ToLower(opts.InputType), Input: opts.Input}
+		return store.OpenSQLiteStore(opts.Output, resumed, meta)
+	}
+
+	streamOptions := fileutils.StreamOptions{
+		Fields:  maputils.StripStrings(strings.Split(opts.Fields, ",")),
+		ListSep: opts.ListSep,
+		Webhook: fileutils.WebhookOptions{
+			Secret:        opts.WebhookSecret,
+			BatchSize:     opts.WebhookBatch,
+			FlushInterval: time.Duration(opts.WebhookInterval) * time.Second,
+			MaxRetries:    opts.WebhookRetries,
+			Timeout:       time.Duration(opts.WebhookTimeout) * time.Second,
+		},
+	}
+	if opts.OutputTemplate != "" {
+		if outputType != "txt" && outputType != "sys" {
+			return nil, fmt.Errorf("--output-template only applies to txt/sys output, got %s", outputType)
+		}
+		// 聚合输出时模板的数据为 report.Group, 内置模板针对单条结果
+		if opts.GroupBy != "" && isBuiltinTemplate(opts.OutputTemplate) {
+			return nil, fmt.Errorf("builtin template %s does not apply to --group-by, use a custom template on the group fields", opts.OutputTemplate)
+		}
+		tmpl, err := report.ParseOutputTemplate(opts.OutputTemplate)
+		if err != nil {
+			return nil, err
+		}
+		streamOptions.Template, streamOptions.TemplateHeader = tmpl.Template, tmpl.Header
+	}
+	writer, err := fileutils.OpenStreamWriterWithOptions(opts.OutputType, opts.Output, resumed, streamOptions)
+	if err != nil || opts.GroupBy == "" {
+		return writer, err
+	}
+	// 收集全部结果, 结束时输出聚合后的分组
+	return report.NewGroupWriter(opts.GroupBy, writer)
+}
+
+// isBuiltinTemplate 判断是否为内置输出模板名称
+func isBuiltinTemplate(value string) bool {
+	for _, name := range report.BuiltinTemplateNames() {
+		if name == value {
+			return true
+		}
+	}
+	return false
+}
+
+// newResultShape 使用 --include/--exclude 时按字段分组构造输出结构, 未使用时返回 nil, 保持各输出详细程度原有的格式
+func newResultShape(opts *Options) (*analyzer.ResultShape, error) {
+	if opts.Include == "" && opts.Exclude == "" {
+		return nil, nil
+	}
+	if opts.OutputURL {
+		return nil, fmt.Errorf("--include/--exclude do not apply to --output-url")
+	}
+	if useFullInfo(opts) {
+		return nil, fmt.Errorf("--include/--exclude do not apply to xlsx/html/sqlite output, --output-template, --real-ips or --group-by")
+	}
+	groups, err := analyzer.SelectFieldGroups(opts.OutputLevel,
+		strings.Split(opts.Include, ","), strings.Split(opts.Exclude, ","))
+	if err != nil {
+		return nil, err
+	}
+	return analyzer.NewResultShape(groups, isVersionedOutput(opts.OutputType)), nil
+}
+
+// buildOutputItem 根据输出详细程度构造单条输出数据, 返回 nil 表示该条结果不输出
+// outputURL 为 true 时输出重建的 scheme://host:port, 供后续扫描工具使用
+func buildOutputItem(outputLevel int, outputURL bool, record *pipeline.Record) interface{} {
+	if outputURL {
+		// 无效目标和作为整体分析的区间没有主机
+		if record.Result.Status == analyzer.StatusInvalid || record.Result.IsRange {
+			return nil
+		}
+		return classify.BuildURL(record.Result.Scheme, record.Result.FMT, record.Result.Port)
+	}
+
+	switch outputLevel {
+	case 1:
+		// 仅输出fmt部分的内容, 无效目标没有fmt
+		if record.Result.Status == analyzer.StatusInvalid {
+			return nil
+		}
+		return record.Result.FMT
+	case 3:
+		// 合并 checkResult 到 checkInfo
+		return analyzer.MergeCheckResultToCheckInfo(record.Info, record.Result)
+	default:
+		// 输出 checkResult
+		return record.Result
+	}
+}
+
+// 带结构版本的 jsonl 输出条目, 便于下游按版本解析
+type (
+	versionedResult struct {
+		SchemaVersion int `json:"schema_version"`
+		analyzer.CheckResult
+	}
+	versionedInfo struct {
+		SchemaVersion int `json:"schema_version"`
+		*analyzer.CheckInfo
+	}
+	versionedFMT struct {
+		SchemaVersion int    `json:"schema_version"`
+		FMT           string `json:"fmt"`
+	}
+	versionedURL struct {
+		SchemaVersion int    `json:"schema_version"`
+		URL           string `json:"url"`
+	}
+)
+
+// versionedItem 为 jsonl 输出的每条结果添加 schema_version 字段, 字符串结果转换为对象
+func versionedItem(outputURL bool, item interface{}) interface{} {
+	version := analyzer.ResultSchemaVersion
+	switch v := item.(type) {
+	case analyzer.CheckResult:
+		return versionedResult{SchemaVersion: version, CheckResult: v}
+	case *analyzer.CheckInfo:
+		return versionedInfo{SchemaVersion: version, CheckInfo: v}
+	case string:
+		if outputURL {
+			return versionedURL{SchemaVersion: version, URL: v}
+		}
+		return versionedFMT{SchemaVersion: version, FMT: v}
+	default:
+		return item
+	}
+}
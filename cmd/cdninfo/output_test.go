@@ -0,0 +1,166 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+	"github.com/winezer0/cdninfo/internal/pipeline"
+)
+
+func TestOutputSinkOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		want    []Options // 只比较 OutputType/Output/RealIPs/OutputTemplate/GroupBy/Include
+		wantErr string
+	}{
+		{
+			name: "real ips nmap",
+			opts: Options{Outs: []string{"real-ips-nmap:targets.txt", "real-ips:ips.txt"}, OutputTemplate: "hosts", GroupBy: "asn"},
+			want: []Options{
+				{OutputType: "txt", Output: "targets.txt", RealIPs: "nmap"},
+				{OutputType: "txt", Output: "ips.txt", RealIPs: "list"},
+			},
+		},
+		{
+			name: "template and group by per type",
+			opts: Options{Outs: []string{"txt:hosts.txt", "json", "sqlite:history.db"}, OutputTemplate: "hosts", GroupBy: "asn"},
+			want: []Options{
+				{OutputType: "txt", Output: "hosts.txt", OutputTemplate: "hosts", GroupBy: "asn"},
+				{OutputType: "json", Output: "result.json", GroupBy: "asn"},
+				{OutputType: "sqlite", Output: "history.db"},
+			},
+		},
+		{
+			name: "include ignored by reports",
+			opts: Options{Outs: []string{"csv:a.csv", "xlsx:a.xlsx"}, Include: "dns"},
+			want: []Options{
+				{OutputType: "csv", Output: "a.csv", Include: "dns"},
+				{OutputType: "xlsx", Output: "a.xlsx"},
+			},
+		},
+		{
+			name: "sys ignores path",
+			opts: Options{Outs: []string{"sys:ignored", "csv"}},
+			want: []Options{{OutputType: "sys"}, {OutputType: "csv", Output: "result.csv"}},
+		},
+		{name: "duplicate path", opts: Options{Outs: []string{"json:out.txt", "txt:out.txt"}}, wantErr: "out.txt is used by another output"},
+		{name: "duplicate sys", opts: Options{Outs: []string{"sys", "sys"}}, wantErr: "stdout is used by another output"},
+		{name: "unknown type", opts: Options{Outs: []string{"yaml:result.yaml"}}, wantErr: "unsupported type yaml"},
+		{name: "unknown real ips format", opts: Options{Outs: []string{"real-ips-xml:ips.xml"}}, wantErr: "unsupported real ip format"},
+		{name: "real ips flag", opts: Options{Outs: []string{"json"}, RealIPs: "nmap"}, wantErr: "--real-ips does not apply to --out"},
+	}
+	for _, tt := range tests {
+		sinks, err := outputSinkOptions(&tt.opts)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: got error %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		var got []Options
+		for _, sink := range sinks {
+			got = append(got, Options{OutputType: sink.OutputType, Output: sink.Output, RealIPs: sink.RealIPs,
+				OutputTemplate: sink.OutputTemplate, GroupBy: sink.GroupBy, Include: sink.Include})
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildOutputItem(t *testing.T) {
+	ok := &pipeline.Record{
+		Info: &analyzer.CheckInfo{RAW: "https://a.com:8443/x", FMT: "a.com", A: []string{"1.1.1.1"}},
+		Result: analyzer.CheckResult{RAW: "https://a.com:8443/x", FMT: "a.com", Scheme: "https", Port: "8443", Status: analyzer.StatusOK,
+			IsCdn: true, CdnCompany: "Cloudflare"},
+	}
+	invalid := &pipeline.Record{
+		Info:   &analyzer.CheckInfo{RAW: "bad"},
+		Result: analyzer.CheckResult{RAW: "bad", Status: analyzer.StatusInvalid},
+	}
+	block := &pipeline.Record{
+		Info:   &analyzer.CheckInfo{RAW: "10.0.0.0/8", FMT: "10.0.0.0/8"},
+		Result: analyzer.CheckResult{RAW: "10.0.0.0/8", FMT: "10.0.0.0/8", Status: analyzer.StatusOK, IsRange: true},
+	}
+
+	tests := []struct {
+		name      string
+		level     int
+		outputURL bool
+		record    *pipeline.Record
+		want      interface{}
+	}{
+		{"level 1", 1, false, ok, "a.com"},
+		{"level 1 invalid", 1, false, invalid, nil},
+		{"level 2", 2, false, ok, ok.Result},
+		{"level 2 invalid", 2, false, invalid, invalid.Result},
+		{"url", 2, true, ok, "https://a.com:8443"},
+		{"url invalid", 3, true, invalid, nil},
+		{"url range block", 1, true, block, nil},
+	}
+	for _, tt := range tests {
+		if got := buildOutputItem(tt.level, tt.outputURL, tt.record); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+
+	// -l 3 合并分析结果到 CheckInfo
+	info, isInfo := buildOutputItem(3, false, ok).(*analyzer.CheckInfo)
+	if !isInfo || info.FMT != "a.com" || !info.IsCdn || info.CdnCompany != "Cloudflare" || len(info.A) != 1 {
+		t.Errorf("level 3: got %#v", info)
+	}
+}
+
+func TestVersionedItem(t *testing.T) {
+	version := analyzer.ResultSchemaVersion
+	result := analyzer.CheckResult{FMT: "a.com"}
+	info := &analyzer.CheckInfo{FMT: "a.com"}
+
+	tests := []struct {
+		name      string
+		outputURL bool
+		item      interface{}
+		want      interface{}
+	}{
+		{"result", false, result, versionedResult{SchemaVersion: version, CheckResult: result}},
+		{"info", false, info, versionedInfo{SchemaVersion: version, CheckInfo: info}},
+		{"fmt", false, "a.com", versionedFMT{SchemaVersion: version, FMT: "a.com"}},
+		{"url", true, "http://a.com:80", versionedURL{SchemaVersion: version, URL: "http://a.com:80"}},
+		{"other", false, 1, 1},
+	}
+	for _, tt := range tests {
+		if got := versionedItem(tt.outputURL, tt.item); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckResumeOutputs(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{"stream", Options{OutputType: "jsonl"}, false},
+		{"sqlite", Options{OutputType: "sqlite"}, false},
+		{"report", Options{OutputType: "xlsx"}, true},
+		{"real ips", Options{OutputType: "txt", RealIPs: "list"}, true},
+		{"group by", Options{OutputType: "json", GroupBy: "asn"}, true},
+		{"out stream", Options{Outs: []string{"jsonl", "sqlite:a.db"}}, false},
+		{"out report", Options{Outs: []string{"jsonl", "html:a.html"}}, true},
+		{"out real ips", Options{Outs: []string{"csv", "real-ips:ips.txt"}}, true},
+		{"out group by ignored by sqlite", Options{Outs: []string{"sqlite:a.db"}, GroupBy: "asn"}, false},
+	}
+	for _, tt := range tests {
+		if err := checkResumeOutputs(&tt.opts); (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
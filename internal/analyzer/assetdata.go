@@ -1,15 +1,41 @@
 package analyzer
 
 import (
+	"time"
+
+	"github.com/winezer0/cdninfo/pkg/domaininfo/dnsquery"
 	"github.com/winezer0/ipinfo/pkg/asninfo"
 )
 
+// 目标处理状态, 域名解析失败时使用 dnsquery 中的解析状态
+const (
+	StatusOK      = "ok"
+	StatusInvalid = "invalid"
+)
+
+// ResultSchemaVersion 输出结果的结构版本, 仅新增字段时不变, 删除、重命名字段或改变字段含义时递增
+const ResultSchemaVersion = 1
+
 // CheckInfo 用于保存资产结果时间的类型
 type CheckInfo struct {
 	RAW     string `json:"raw"`     // 存储原始输入信息
 	FMT     string `json:"fmt"`     // 存储格式化后的输入信息（可选）
 	IsIpv4  bool   `json:"isIpv4"`  // 存储格式化后的输入信息（可选）
 	FromUrl bool   `json:"fromUrl"` // 存储格式化后的输入信息（可选）
+	Scheme  string `json:"scheme"`  // URL 输入的协议
+	Port    string `json:"port"`    // URL 或 host:port 输入的端口
+	Path    string `json:"path"`    // URL 输入的路径
+	IsRange bool   `json:"isRange"` // 是否作为整体分析的 CIDR/IP 区间
+
+	Unicode     string `json:"unicode"`     // 域名的 Unicode 形式, FMT 为 punycode 形式
+	Wildcard    bool   `json:"wildcard"`    // 是否来自通配符域名, FMT 为基础域名
+	Registrable string `json:"registrable"` // 可注册域名(eTLD+1)
+	Subdomain   string `json:"subdomain"`   // 子域名部分
+
+	Extra map[string]string `json:"extra"` // 输入中附带的额外字段, 如资产负责人、端口
+
+	Status string `json:"status"` // 处理状态: ok/invalid/no_records/nxdomain/servfail/timeout/error
+	Error  string `json:"error"`  // 非 ok 状态的原因
 
 	A     []string `json:"A"`     // A记录
 	AAAA  []string `json:"AAAA"`  // AAAA记录
@@ -18,6 +44,9 @@ type CheckInfo struct {
 	MX    []string `json:"MX"`    // MX记录
 	TXT   []string `json:"TXT"`   // TXT记录
 
+	DNSError    *dnsquery.ErrorSummary `json:"DNSError,omitempty"` // DNS查询错误汇总
+	QueryFailed bool                   `json:"QueryFailed"`        // DNS查询失败，记录可能不完整
+
 	Ipv4Locate []map[string]string `json:"Ipv4Locate"` // A记录的IP解析信息
 	Ipv6Locate []map[string]string `json:"Ipv6Locate"` // AAAA记录的IP解析信息
 
@@ -35,6 +64,37 @@ type CheckInfo struct {
 
 	IpSize      int  `json:"IpSize"`
 	IpSizeIsCdn bool `json:"IpSizeIsCdn"`
+
+	Evidence []MatchEvidence `json:"Evidence"` // CDN/WAF/Cloud 判定的命中依据
+
+	Timing Timing `json:"-"` // 各处理阶段的耗时, 仅在 --include timing 时输出
+}
+
+// Timing 目标所在批次各处理阶段的耗时(毫秒), 流水线按批次处理, 同一批次的目标耗时相同
+type Timing struct {
+	BatchResolveMs  int64     `json:"batch_resolve_ms"`  // 批次的DNS解析
+	BatchIPInfoMs   int64     `json:"batch_ipinfo_ms"`   // 批次的IP归属地和ASN查询
+	BatchAnalyzeMs  int64     `json:"batch_analyze_ms"`  // 批次的CDN/WAF/Cloud 分析
+	BatchFinishedAt time.Time `json:"batch_finished_at"` // 批次分析完成时间
+}
+
+// 命中依据的类型
+const (
+	EvidenceCNAME  = "cname"   // CNAME 命中厂商关键字或正则
+	EvidenceKeys   = "keys"    // IP 归属地命中厂商关键字
+	EvidenceASN    = "asn"     // ASN 属于厂商
+	EvidenceIP     = "ip"      // IP 在厂商 CIDR 内
+	EvidenceRange  = "range"   // 区间整体落在厂商 CIDR 内
+	EvidenceIPSize = "ip_size" // 解析出的 IP 数量超过阈值
+)
+
+// MatchEvidence 目标被判定为 CDN/WAF/Cloud 的依据
+type MatchEvidence struct {
+	Category string `json:"category"` // cdn/waf/cloud
+	Company  string `json:"company"`  // 厂商名称, ip_size 类型为空
+	Type     string `json:"type"`     // 命中类型: cname/keys/asn/ip/range/ip_size
+	Value    string `json:"value"`    // 目标中命中的值, 如 CNAME、归属地、ASN、IP
+	Rule     string `json:"rule"`     // 命中的规则, 如关键字、正则、ASN 号、CIDR
 }
 
 // NewDomainCheckInfo 初始化一个新的 CheckInfo 实例
@@ -53,6 +113,7 @@ func NewIPCheckInfo(raw, fmt string, isIpv4 bool, fromUrl bool) *CheckInfo {
 		FMT:     fmt,
 		IsIpv4:  isIpv4,
 		FromUrl: fromUrl,
+		Status:  StatusOK,
 	}
 
 	if isIpv4 {
@@ -63,3 +124,23 @@ func NewIPCheckInfo(raw, fmt string, isIpv4 bool, fromUrl bool) *CheckInfo {
 
 	return result
 }
+
+// NewInvalidCheckInfo 初始化一个无法识别的目标对应的 CheckInfo 实例
+func NewInvalidCheckInfo(raw, errMsg string) *CheckInfo {
+	return &CheckInfo{
+		RAW:    raw,
+		Status: StatusInvalid,
+		Error:  errMsg,
+	}
+}
+
+// NewRangeCheckInfo 初始化一个按整体分析的 CIDR/IP 区间对应的 CheckInfo 实例
+func NewRangeCheckInfo(raw, fmt string, isIpv4 bool) *CheckInfo {
+	return &CheckInfo{
+		RAW:     raw,
+		FMT:     fmt,
+		IsIpv4:  isIpv4,
+		IsRange: true,
+		Status:  StatusOK,
+	}
+}
@@ -1,14 +1,31 @@
 package analyzer
 
 import (
+	"github.com/winezer0/cdninfo/pkg/domaininfo/dnsquery"
 	"github.com/winezer0/cdninfo/pkg/maputils"
 	"github.com/winezer0/ipinfo/pkg/asninfo"
+	"strconv"
+	"strings"
 	"sync"
 )
 
+// ipSizeLimit 解析出的 IP 数量超过该值时认为符合 CDN 特征
+const ipSizeLimit = 3
+
 type CheckResult struct {
 	RAW          string `json:"raw"`
 	FMT          string `json:"fmt"`
+	Unicode      string `json:"unicode"`
+	Wildcard     bool   `json:"wildcard"`
+	Registrable  string `json:"registrable"`
+	Subdomain    string `json:"subdomain"`
+	Scheme       string `json:"scheme"`
+	Port         string `json:"port"`
+	Path         string `json:"path"`
+	Status       string `json:"status"`
+	Error        string `json:"error"`
+	QueryFailed  bool   `json:"query_failed"`
+	IsRange      bool   `json:"is_range"`
 	IsCdn        bool   `json:"is_cdn"`
 	CdnCompany   string `json:"cdn_company"`
 	IsWaf        bool   `json:"is_waf"`
@@ -17,12 +34,48 @@ type CheckResult struct {
 	CloudCompany string `json:"cloud_company"`
 	IpSizeIsCdn  bool   `json:"ip_size_is_cdn"`
 	IpSize       int    `json:"ip_size"`
+
+	Evidence []MatchEvidence   `json:"evidence"`
+	Extra    map[string]string `json:"extra"`
+}
+
+// newCheckResult 从 CheckInfo 复制目标本身的信息, 分析结果字段为空
+func newCheckResult(checkInfo *CheckInfo) CheckResult {
+	return CheckResult{
+		RAW:         checkInfo.RAW,
+		FMT:         checkInfo.FMT,
+		Unicode:     checkInfo.Unicode,
+		Wildcard:    checkInfo.Wildcard,
+		Registrable: checkInfo.Registrable,
+		Subdomain:   checkInfo.Subdomain,
+		Status:      checkInfo.Status,
+		Error:       checkInfo.Error,
+		Scheme:      checkInfo.Scheme,
+		Port:        checkInfo.Port,
+		Path:        checkInfo.Path,
+		Extra:       checkInfo.Extra,
+	}
+}
+
+// NewErrorCheckResult 分析失败时生成的结果, 状态为 error
+func NewErrorCheckResult(checkInfo *CheckInfo, err error) CheckResult {
+	checkResult := newCheckResult(checkInfo)
+	checkResult.Status = dnsquery.StatusError
+	checkResult.Error = err.Error()
+	return checkResult
 }
 
 func checkCDN(cdnData *CDNData, checkInfo *CheckInfo) (CheckResult, error) {
-	checkResult := CheckResult{
-		RAW: checkInfo.RAW,
-		FMT: checkInfo.FMT,
+	checkResult := newCheckResult(checkInfo)
+	if checkInfo.Status == StatusInvalid {
+		return checkResult, nil
+	}
+	if checkInfo.IsRange {
+		return checkRangeBlock(cdnData, checkResult)
+	}
+	checkResult.QueryFailed = queryFailed(checkInfo)
+	if checkResult.QueryFailed && checkResult.Error == "" && checkInfo.DNSError != nil {
+		checkResult.Error = "dns query failed for " + strings.Join(checkInfo.DNSError.FailedTypes, "/")
 	}
 
 	cnameList := checkInfo.CNAME
@@ -37,37 +90,62 @@ func checkCDN(cdnData *CDNData, checkInfo *CheckInfo) (CheckResult, error) {
 	)
 
 	// 判断 IP 数量是否符合 CDN 特征
-	checkResult.IpSizeIsCdn, checkResult.IpSize = IpsSizeIsCdn(ipList, 3)
+	checkResult.IpSizeIsCdn, checkResult.IpSize = IpsSizeIsCdn(ipList, ipSizeLimit)
+	if checkResult.IpSizeIsCdn {
+		checkResult.Evidence = append(checkResult.Evidence, MatchEvidence{
+			Category: "cdn",
+			Type:     EvidenceIPSize,
+			Value:    strconv.Itoa(checkResult.IpSize),
+			Rule:     "> " + strconv.Itoa(ipSizeLimit),
+		})
+	}
 
 	// 封装分类检查逻辑
 	type categoryHandler struct {
+		name  string
 		db    Category
 		setFn func(bool, string)
 	}
 
 	categories := []categoryHandler{
 		{
+			name:  "cdn",
 			db:    cdnData.CDN,
 			setFn: func(b bool, s string) { checkResult.IsCdn, checkResult.CdnCompany = b, s },
 		},
 		{
+			name:  "waf",
 			db:    cdnData.WAF,
 			setFn: func(b bool, s string) { checkResult.IsWaf, checkResult.WafCompany = b, s },
 		},
 		{
+			name:  "cloud",
 			db:    cdnData.CLOUD,
 			setFn: func(b bool, s string) { checkResult.IsCloud, checkResult.CloudCompany = b, s },
 		},
 	}
 
 	for _, cat := range categories {
-		match, company := CheckCategory(cat.db, ipList, asnList, cnameList, ipLocateList)
-		cat.setFn(match, company)
+		match, evidence := CheckCategoryEvidence(cat.db, ipList, asnList, cnameList, ipLocateList)
+		cat.setFn(match, evidence.Company)
+		if match {
+			evidence.Category = cat.name
+			checkResult.Evidence = append(checkResult.Evidence, evidence)
+		}
 	}
 
 	return checkResult, nil
 }
 
+// queryFailed 判断DNS查询是否失败, 失败时空的 A/CNAME 记录不能说明目标不是CDN
+func queryFailed(checkInfo *CheckInfo) bool {
+	switch checkInfo.Status {
+	case dnsquery.StatusServFail, dnsquery.StatusTimeout, dnsquery.StatusError:
+		return true
+	}
+	return checkInfo.DNSError.HasFailedType("A") || checkInfo.DNSError.HasFailedType("CNAME")
+}
+
 func getUniqueOrgNumbers(asnInfos []asninfo.ASNInfo) []uint64 {
 	seen := make(map[uint64]struct{})
 	result := make([]uint64, 0, len(asnInfos))
@@ -0,0 +1,57 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"net"
+
+	"github.com/winezer0/cdninfo/pkg/classify"
+)
+
+// checkRangeBlock 将 CIDR/IP 区间作为整体分析, 仅当整个区间落在某个厂商的 CIDR 内时判定命中
+func checkRangeBlock(cdnData *CDNData, checkResult CheckResult) (CheckResult, error) {
+	checkResult.IsRange = true
+
+	start, end, _, ok := classify.ParseIPRange(checkResult.FMT)
+	if !ok {
+		checkResult.Status = StatusInvalid
+		checkResult.Error = "invalid ip range: " + checkResult.FMT
+		return checkResult, fmt.Errorf("invalid ip range: %s", checkResult.FMT)
+	}
+
+	// 超出 int 范围的 IPv6 区间按最大值记录
+	size := classify.RangeSize(start, end)
+	if size.IsInt64() && size.Int64() <= math.MaxInt32 {
+		checkResult.IpSize = int(size.Int64())
+	} else {
+		checkResult.IpSize = math.MaxInt32
+	}
+
+	checkResult.IsCdn, checkResult.CdnCompany = rangeInMap(&checkResult, "cdn", start, end, cdnData.CDN.IP)
+	checkResult.IsWaf, checkResult.WafCompany = rangeInMap(&checkResult, "waf", start, end, cdnData.WAF.IP)
+	checkResult.IsCloud, checkResult.CloudCompany = rangeInMap(&checkResult, "cloud", start, end, cdnData.CLOUD.IP)
+	return checkResult, nil
+}
+
+// rangeInMap 检查区间是否完整落在某个厂商的某个 CIDR 内, 区间连续, 起止IP都在同一 CIDR 内即可, 命中时记录依据
+func rangeInMap(checkResult *CheckResult, category string, start, end net.IP, ipsMap map[string][]string) (bool, string) {
+	for companyName, cidrList := range ipsMap {
+		for _, cidr := range cidrList {
+			_, network, err := net.ParseCIDR(cidr)
+			if err != nil {
+				continue
+			}
+			if network.Contains(start) && network.Contains(end) {
+				checkResult.Evidence = append(checkResult.Evidence, MatchEvidence{
+					Category: category,
+					Company:  companyName,
+					Type:     EvidenceRange,
+					Value:    checkResult.FMT,
+					Rule:     cidr,
+				})
+				return true, companyName
+			}
+		}
+	}
+	return false, ""
+}
@@ -0,0 +1,69 @@
+package analyzer
+
+import (
+	"math"
+	"testing"
+
+	"github.com/winezer0/cdninfo/pkg/classify"
+)
+
+func TestCheckRangeBlock(t *testing.T) {
+	cdnData := &CDNData{
+		CDN:   Category{IP: map[string][]string{"cloudflare": {"104.16.0.0/13"}}},
+		WAF:   Category{IP: map[string][]string{"waf": {"104.16.0.0/24"}}},
+		CLOUD: Category{IP: map[string][]string{"aws": {"2001:db8::/32"}}},
+	}
+
+	tests := []struct {
+		name       string
+		target     string
+		isCdn      bool
+		cdnCompany string
+		isWaf      bool
+		isCloud    bool
+		ipSize     int
+	}{
+		{"block in cidr", "104.16.1.0/24", true, "cloudflare", false, false, 256},
+		{"range in cidr", "104.16.0.1-20", true, "cloudflare", true, false, 20},
+		{"partial overlap", "104.15.255.0-104.16.0.10", false, "", false, false, 267},
+		{"outside", "8.8.8.0/24", false, "", false, false, 256},
+		{"ipv6 size capped", "2001:db8::/64", false, "", false, true, math.MaxInt32},
+	}
+
+	for _, tt := range tests {
+		_, _, format, ok := classify.ParseIPRange(tt.target)
+		if !ok {
+			t.Fatalf("%s: invalid range %s", tt.name, tt.target)
+		}
+		result, err := checkRangeBlock(cdnData, CheckResult{RAW: tt.target, FMT: format})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !result.IsRange || result.IsCdn != tt.isCdn || result.CdnCompany != tt.cdnCompany ||
+			result.IsWaf != tt.isWaf || result.IsCloud != tt.isCloud || result.IpSize != tt.ipSize {
+			t.Errorf("%s: got %+v", tt.name, result)
+		}
+	}
+
+	result, err := checkRangeBlock(cdnData, CheckResult{FMT: "not-a-range"})
+	if err == nil || result.Status != StatusInvalid {
+		t.Errorf("invalid range: got status %q, err %v", result.Status, err)
+	}
+}
+
+func TestCheckRangeBlockOverRangeMax(t *testing.T) {
+	cdnData := &CDNData{CDN: Category{IP: map[string][]string{"cloudflare": {"104.16.0.0/13"}}}}
+
+	// 超过 --range-max 的区间不展开, 整体分析时仍按完整区间判定
+	start, end, format, _ := classify.ParseIPRange("104.16.0.0/16")
+	if _, err := classify.ExpandIPRange(start, end, 256); err == nil {
+		t.Fatal("expected /16 to exceed range max 256")
+	}
+	result, err := checkCDN(cdnData, NewRangeCheckInfo("104.16.0.0/16", format, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsCdn || result.CdnCompany != "cloudflare" || result.IpSize != 65536 {
+		t.Errorf("got %+v", result)
+	}
+}
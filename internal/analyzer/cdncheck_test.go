@@ -1,9 +1,11 @@
 package analyzer
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 
+	"github.com/winezer0/cdninfo/pkg/domaininfo/dnsquery"
 	"github.com/winezer0/ipinfo/pkg/asninfo"
 )
 
@@ -24,3 +26,33 @@ func TestGetUniqueOrgNumbers(t *testing.T) {
 	}
 }
 
+func TestCheckCategoryEvidence(t *testing.T) {
+	category := Category{
+		IP:    map[string][]string{"Cloudflare": {"104.16.0.0/13"}},
+		CNAME: map[string][]string{"Akamai": {"akamaiedge.net"}},
+	}
+
+	ok, evidence := CheckCategoryEvidence(category, []string{"104.16.1.1"}, nil, nil, nil)
+	want := MatchEvidence{Company: "Cloudflare", Type: EvidenceIP, Value: "104.16.1.1", Rule: "104.16.0.0/13"}
+	if !ok || evidence != want {
+		t.Fatalf("unexpected ip evidence, got=%+v want=%+v", evidence, want)
+	}
+
+	ok, evidence = CheckCategoryEvidence(category, nil, nil, []string{"e1.a.akamaiedge.net."}, nil)
+	want = MatchEvidence{Company: "Akamai", Type: EvidenceCNAME, Value: "e1.a.akamaiedge.net.", Rule: "akamaiedge.net"}
+	if !ok || evidence != want {
+		t.Fatalf("unexpected cname evidence, got=%+v want=%+v", evidence, want)
+	}
+}
+
+func TestNewErrorCheckResult(t *testing.T) {
+	checkInfo := NewDomainCheckInfo("https://a.com/x", "a.com", true)
+	checkInfo.Extra = map[string]string{"owner": "ops"}
+	result := NewErrorCheckResult(checkInfo, errors.New("analyze failed"))
+	if result.RAW != "https://a.com/x" || result.FMT != "a.com" || result.Extra["owner"] != "ops" {
+		t.Errorf("target fields not copied: %+v", result)
+	}
+	if result.Status != dnsquery.StatusError || result.Error != "analyze failed" {
+		t.Errorf("got status %q error %q", result.Status, result.Error)
+	}
+}
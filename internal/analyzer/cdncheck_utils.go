@@ -23,6 +23,12 @@ var regexCache sync.Map
 var regexMark = []string{"]", ")", "}", "*", "+", "^", "$", "?", "|", "\\"}
 
 func containKeysSupportRegex(str string, keys []string) bool {
+	_, ok := matchKeysSupportRegex(str, keys)
+	return ok
+}
+
+// matchKeysSupportRegex 返回第一个命中的关键字或正则
+func matchKeysSupportRegex(str string, keys []string) (string, bool) {
 	str = strings.Trim(str, ".")
 	strLower := strings.ToLower(str)
 
@@ -35,7 +41,7 @@ func containKeysSupportRegex(str string, keys []string) bool {
 				regex, err := regexp.Compile("(?i)" + key)
 				if err != nil {
 					if strings.Contains(strLower, keyLower) {
-						return true
+						return key, true
 					}
 					continue
 				}
@@ -43,27 +49,27 @@ func containKeysSupportRegex(str string, keys []string) bool {
 			}
 
 			if re, ok := val.(*regexp.Regexp); ok && re.MatchString(str) {
-				return true
+				return key, true
 			}
 		} else {
 			if strings.Contains(strLower, keyLower) {
-				return true
+				return key, true
 			}
 		}
 	}
-	return false
+	return "", false
 }
 
-// keysInMap 检查一组 CNAME 是否命中某个 CDN 厂商，并复用 containsAny 实现
-func keysInMap(cnames []string, cnamesMap map[string][]string) (bool, string) {
+// keysInMap 检查一组 CNAME 是否命中某个 CDN 厂商，并返回命中依据
+func keysInMap(cnames []string, cnamesMap map[string][]string) (bool, MatchEvidence) {
 	for _, cname := range cnames {
 		for companyName, cdnCNames := range cnamesMap {
-			if ok := containKeysSupportRegex(cname, cdnCNames); ok {
-				return true, companyName
+			if key, ok := matchKeysSupportRegex(cname, cdnCNames); ok {
+				return true, MatchEvidence{Company: companyName, Value: cname, Rule: key}
 			}
 		}
 	}
-	return false, ""
+	return false, MatchEvidence{}
 }
 
 // buildIpRanger 构建一个 CIDR 查找器（ranger）用于快速判断 IP 是否在某组 CIDR 范围内
@@ -79,28 +85,32 @@ func buildIpRanger(cidrList []string) cidranger.Ranger {
 	return ranger
 }
 
-// ipInRanger 使用预构建的 CIDR 查找器来判断 IP 是否属于该 CDN 厂商的网段
-func ipInRanger(ip string, ranger cidranger.Ranger) bool {
+// ipInRanger 使用预构建的 CIDR 查找器来判断 IP 是否属于该 CDN 厂商的网段, 并返回命中的 CIDR
+func ipInRanger(ip string, ranger cidranger.Ranger) (string, bool) {
 	parsedIP := net.ParseIP(ip)
 	if parsedIP == nil {
-		return false
+		return "", false
 	}
 
-	contains, err := ranger.Contains(parsedIP)
-	return err == nil && contains
+	entries, err := ranger.ContainingNetworks(parsedIP)
+	if err != nil || len(entries) == 0 {
+		return "", false
+	}
+	network := entries[0].Network()
+	return network.String(), true
 }
 
-// ipsInMap 检查多个 IP 是否命中某个 CDN 厂商，并返回厂商名称
-func ipsInMap(ips []string, ipsMap map[string][]string) (bool, string) {
+// ipsInMap 检查多个 IP 是否命中某个 CDN 厂商，并返回命中依据
+func ipsInMap(ips []string, ipsMap map[string][]string) (bool, MatchEvidence) {
 	for companyName, cdnIPs := range ipsMap {
 		ranger := buildIpRanger(cdnIPs) // 为每个厂商只构建一次 CIDR 查找器
 		for _, ip := range ips {
-			if ipInRanger(ip, ranger) {
-				return true, companyName
+			if cidr, ok := ipInRanger(ip, ranger); ok {
+				return true, MatchEvidence{Company: companyName, Value: ip, Rule: cidr}
 			}
 		}
 	}
-	return false, ""
+	return false, MatchEvidence{}
 }
 
 // asnInList 检查传入的 uint64 格式的 ASN 号是否在 CDN 厂商的 ASN 列表中
@@ -117,16 +127,17 @@ func asnInList(asn uint64, asnList []string) bool {
 	return false
 }
 
-// asnInMap 检查多个 ASN 是否属于某个 CDN 厂商，并返回厂商名称
-func asnInMap(asns []uint64, cdnASNsMap map[string][]string) (bool, string) {
+// asnInMap 检查多个 ASN 是否属于某个 CDN 厂商，并返回命中依据
+func asnInMap(asns []uint64, cdnASNsMap map[string][]string) (bool, MatchEvidence) {
 	for _, asn := range asns {
 		for companyName, cdnASNs := range cdnASNsMap {
 			if asnInList(asn, cdnASNs) {
-				return true, companyName
+				asnStr := strconv.FormatUint(asn, 10)
+				return true, MatchEvidence{Company: companyName, Value: "AS" + asnStr, Rule: asnStr}
 			}
 		}
 	}
-	return false, ""
+	return false, MatchEvidence{}
 }
 
 // IpsSizeIsCdn 检查多个 IP 是否命中某个 CDN 厂商，并返回厂商名称
@@ -136,28 +147,41 @@ func IpsSizeIsCdn(ips []string, limitSize int) (bool, int) {
 }
 
 func CheckCategory(categoryMap Category, ipList []string, asnList []uint64, cnameList []string, ipLocateList []string) (bool, string) {
+	ok, evidence := CheckCategoryEvidence(categoryMap, ipList, asnList, cnameList, ipLocateList)
+	return ok, evidence.Company
+}
+
+// CheckCategoryEvidence 与 CheckCategory 相同, 同时返回命中的类型、值和规则
+func CheckCategoryEvidence(categoryMap Category, ipList []string, asnList []uint64, cnameList []string, ipLocateList []string) (bool, MatchEvidence) {
 	// 定义检查项：每个检查项是一个函数调用
-	checks := []func() (bool, string){
-		func() (bool, string) { return keysInMap(cnameList, categoryMap.CNAME) },
-		func() (bool, string) { return keysInMap(ipLocateList, categoryMap.KEYS) },
-		func() (bool, string) { return asnInMap(asnList, categoryMap.ASN) },
-		func() (bool, string) { return ipsInMap(ipList, categoryMap.IP) },
+	checks := []struct {
+		matchType string
+		check     func() (bool, MatchEvidence)
+	}{
+		{EvidenceCNAME, func() (bool, MatchEvidence) { return keysInMap(cnameList, categoryMap.CNAME) }},
+		{EvidenceKeys, func() (bool, MatchEvidence) { return keysInMap(ipLocateList, categoryMap.KEYS) }},
+		{EvidenceASN, func() (bool, MatchEvidence) { return asnInMap(asnList, categoryMap.ASN) }},
+		{EvidenceIP, func() (bool, MatchEvidence) { return ipsInMap(ipList, categoryMap.IP) }},
 	}
 
 	// 依次执行检查
 	for _, check := range checks {
-		if ok, company := check(); ok {
-			return ok, company
+		if ok, evidence := check.check(); ok {
+			evidence.Type = check.matchType
+			return ok, evidence
 		}
 	}
 
-	return false, ""
+	return false, MatchEvidence{}
 }
 
-// GetFmtList 获取NoCDN && NoWAF的fmt数据
+// GetFmtList 获取已分析目标的fmt数据
 func GetFmtList(checkResults []CheckResult) []string {
 	var fmtList []string
 	for _, r := range checkResults {
+		if r.Status == StatusInvalid {
+			continue
+		}
 		fmtList = append(fmtList, r.FMT)
 	}
 	return fmtList
@@ -167,41 +191,56 @@ func GetFmtList(checkResults []CheckResult) []string {
 func FilterNoCdnNoWaf(checkResults []CheckResult) []CheckResult {
 	var nonCDNResult []CheckResult
 	for _, r := range checkResults {
-		if !r.IsCdn && !r.IsWaf && !r.IpSizeIsCdn {
+		if IsNoCdnNoWaf(r) {
 			nonCDNResult = append(nonCDNResult, r)
 		}
 	}
 	return nonCDNResult
 }
 
-// MergeCheckResultsToCheckInfos  通过 FMT 字段匹配对应条目将 checkResults 合并到 checkInfos
+// IsNoCdnNoWaf 判断单个结果是否为 NoCDN && NoWAF, 未能成功解析或查询失败的目标无法判断, 不计入
+func IsNoCdnNoWaf(r CheckResult) bool {
+	return r.Status == StatusOK && !r.QueryFailed && !r.IsCdn && !r.IsWaf && !r.IpSizeIsCdn
+}
+
+// MergeCheckResultsToCheckInfos  通过 RAW 和 FMT 字段匹配对应条目将 checkResults 合并到 checkInfos
+// 不同原始输入可能有相同的 FMT, 仅按 FMT 匹配会合并到错误的条目
 func MergeCheckResultsToCheckInfos(checkInfos []*CheckInfo, checkResults []CheckResult) []*CheckInfo {
-	// 创建 FMT 到 CheckResult 的映射，便于快速查找
-	resultMap := make(map[string]CheckResult)
+	// 创建 RAW+FMT 到 CheckResult 的映射，便于快速查找
+	resultMap := make(map[[2]string]CheckResult)
 	for _, result := range checkResults {
-		resultMap[result.FMT] = result
+		resultMap[[2]string{result.RAW, result.FMT}] = result
 	}
 
 	// 遍历 checkInfos，将对应的 CheckResult 信息合并进去
 	for _, checkInfo := range checkInfos {
 		// 查找对应的 CheckResult
-		if result, ok := resultMap[checkInfo.FMT]; ok {
-			// 合并 CDN 相关信息
-			checkInfo.IsCdn = result.IsCdn
-			checkInfo.CdnCompany = result.CdnCompany
-
-			// 合并 WAF 相关信息
-			checkInfo.IsWaf = result.IsWaf
-			checkInfo.WafCompany = result.WafCompany
-
-			// 合并 Cloud 相关信息
-			checkInfo.IsCloud = result.IsCloud
-			checkInfo.CloudCompany = result.CloudCompany
-
-			// 合并 IP 大小相关信息
-			checkInfo.IpSizeIsCdn = result.IpSizeIsCdn
-			checkInfo.IpSize = result.IpSize
+		if result, ok := resultMap[[2]string{checkInfo.RAW, checkInfo.FMT}]; ok {
+			MergeCheckResultToCheckInfo(checkInfo, result)
 		}
 	}
 	return checkInfos
 }
+
+// MergeCheckResultToCheckInfo 将单个 CheckResult 的分析结论合并到 CheckInfo 中
+func MergeCheckResultToCheckInfo(checkInfo *CheckInfo, result CheckResult) *CheckInfo {
+	// 合并 CDN 相关信息
+	checkInfo.IsCdn = result.IsCdn
+	checkInfo.CdnCompany = result.CdnCompany
+
+	// 合并 WAF 相关信息
+	checkInfo.IsWaf = result.IsWaf
+	checkInfo.WafCompany = result.WafCompany
+
+	// 合并 Cloud 相关信息
+	checkInfo.IsCloud = result.IsCloud
+	checkInfo.CloudCompany = result.CloudCompany
+
+	// 合并 IP 大小相关信息
+	checkInfo.IpSizeIsCdn = result.IpSizeIsCdn
+	checkInfo.IpSize = result.IpSize
+
+	checkInfo.QueryFailed = result.QueryFailed
+	checkInfo.Evidence = result.Evidence
+	return checkInfo
+}
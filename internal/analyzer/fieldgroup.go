@@ -0,0 +1,208 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/winezer0/cdninfo/pkg/domaininfo/dnsquery"
+	"github.com/winezer0/ipinfo/pkg/asninfo"
+)
+
+// 输出字段分组, 通过 --include/--exclude 选择
+const (
+	FieldGroupDNS      = "dns"
+	FieldGroupGeo      = "geo"
+	FieldGroupASN      = "asn"
+	FieldGroupVerdict  = "verdict"
+	FieldGroupEvidence = "evidence"
+	FieldGroupTiming   = "timing"
+)
+
+// FieldGroups 全部字段分组, 也是分组在输出中的顺序
+var FieldGroups = []string{FieldGroupVerdict, FieldGroupEvidence, FieldGroupDNS, FieldGroupGeo, FieldGroupASN, FieldGroupTiming}
+
+// levelFieldGroups 各输出详细程度默认包含的分组, -l 1 只有基础字段
+var levelFieldGroups = map[int][]string{
+	1: {},
+	2: {FieldGroupVerdict, FieldGroupEvidence},
+	3: {FieldGroupVerdict, FieldGroupEvidence, FieldGroupDNS, FieldGroupGeo, FieldGroupASN},
+}
+
+// 各分组的字段, 基础字段和判定结论使用 CheckResult 的名称, 记录类字段使用 CheckInfo 的名称
+type (
+	// ResultBase 始终输出的目标信息和处理状态
+	ResultBase struct {
+		RAW         string            `json:"raw"`
+		FMT         string            `json:"fmt"`
+		Unicode     string            `json:"unicode"`
+		Wildcard    bool              `json:"wildcard"`
+		Registrable string            `json:"registrable"`
+		Subdomain   string            `json:"subdomain"`
+		Scheme      string            `json:"scheme"`
+		Port        string            `json:"port"`
+		Path        string            `json:"path"`
+		Status      string            `json:"status"`
+		Error       string            `json:"error"`
+		QueryFailed bool              `json:"query_failed"`
+		IsRange     bool              `json:"is_range"`
+		Extra       map[string]string `json:"extra"`
+	}
+	// ResultVerdict CDN/WAF/Cloud 判定结论
+	ResultVerdict struct {
+		IsCdn        bool   `json:"is_cdn"`
+		CdnCompany   string `json:"cdn_company"`
+		IsWaf        bool   `json:"is_waf"`
+		WafCompany   string `json:"waf_company"`
+		IsCloud      bool   `json:"is_cloud"`
+		CloudCompany string `json:"cloud_company"`
+		IpSizeIsCdn  bool   `json:"ip_size_is_cdn"`
+		IpSize       int    `json:"ip_size"`
+	}
+	// ResultEvidence 判定的命中依据
+	ResultEvidence struct {
+		Evidence []MatchEvidence `json:"evidence"`
+	}
+	// ResultDNS DNS记录
+	ResultDNS struct {
+		A        []string               `json:"A"`
+		AAAA     []string               `json:"AAAA"`
+		CNAME    []string               `json:"CNAME"`
+		NS       []string               `json:"NS"`
+		MX       []string               `json:"MX"`
+		TXT      []string               `json:"TXT"`
+		DNSError *dnsquery.ErrorSummary `json:"DNSError,omitempty"`
+	}
+	// ResultGeo IP归属地
+	ResultGeo struct {
+		Ipv4Locate []map[string]string `json:"Ipv4Locate"`
+		Ipv6Locate []map[string]string `json:"Ipv6Locate"`
+	}
+	// ResultASN IP所属的ASN
+	ResultASN struct {
+		Ipv4Asn []asninfo.ASNInfo `json:"Ipv4Asn"`
+		Ipv6Asn []asninfo.ASNInfo `json:"Ipv6Asn"`
+	}
+	// ResultTiming 目标所在批次各处理阶段的耗时
+	ResultTiming struct {
+		Timing Timing `json:"timing"`
+	}
+)
+
+// fieldGroupParts 各分组对应的结构体及取值方法
+var fieldGroupParts = map[string]func(info *CheckInfo, result CheckResult) interface{}{
+	FieldGroupVerdict: func(_ *CheckInfo, r CheckResult) interface{} {
+		return ResultVerdict{IsCdn: r.IsCdn, CdnCompany: r.CdnCompany, IsWaf: r.IsWaf, WafCompany: r.WafCompany,
+			IsCloud: r.IsCloud, CloudCompany: r.CloudCompany, IpSizeIsCdn: r.IpSizeIsCdn, IpSize: r.IpSize}
+	},
+	FieldGroupEvidence: func(_ *CheckInfo, r CheckResult) interface{} {
+		return ResultEvidence{Evidence: r.Evidence}
+	},
+	FieldGroupDNS: func(i *CheckInfo, _ CheckResult) interface{} {
+		return ResultDNS{A: i.A, AAAA: i.AAAA, CNAME: i.CNAME, NS: i.NS, MX: i.MX, TXT: i.TXT, DNSError: i.DNSError}
+	},
+	FieldGroupGeo: func(i *CheckInfo, _ CheckResult) interface{} {
+		return ResultGeo{Ipv4Locate: i.Ipv4Locate, Ipv6Locate: i.Ipv6Locate}
+	},
+	FieldGroupASN: func(i *CheckInfo, _ CheckResult) interface{} {
+		return ResultASN{Ipv4Asn: i.Ipv4Asn, Ipv6Asn: i.Ipv6Asn}
+	},
+	FieldGroupTiming: func(i *CheckInfo, _ CheckResult) interface{} {
+		return ResultTiming{Timing: i.Timing}
+	},
+}
+
+// SelectFieldGroups 在输出详细程度默认分组的基础上增加 include 并去掉 exclude 中的分组
+// 同一分组同时出现在 include 和 exclude 中时以 exclude 为准
+func SelectFieldGroups(level int, include, exclude []string) ([]string, error) {
+	selected := make(map[string]bool)
+	for _, group := range levelFieldGroups[level] {
+		selected[group] = true
+	}
+	if err := markFieldGroups(selected, include, true); err != nil {
+		return nil, err
+	}
+	if err := markFieldGroups(selected, exclude, false); err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, group := range FieldGroups {
+		if selected[group] {
+			result = append(result, group)
+		}
+	}
+	return result, nil
+}
+
+func markFieldGroups(selected map[string]bool, groups []string, value bool) error {
+	for _, group := range groups {
+		group = strings.ToLower(strings.TrimSpace(group))
+		if group == "" {
+			continue
+		}
+		if _, ok := fieldGroupParts[group]; !ok {
+			return fmt.Errorf("unsupported field group: %s, supported: %s", group, strings.Join(FieldGroups, "/"))
+		}
+		selected[group] = value
+	}
+	return nil
+}
+
+// ResultShape 按选择的字段分组动态构造的输出结构
+// 分组以匿名字段嵌入, json、csv 展开和输出模板都按普通结构体处理
+type ResultShape struct {
+	groups      []string
+	typ         reflect.Type
+	withVersion bool
+}
+
+var shapeTypes sync.Map // 分组组合到结构体类型的缓存
+
+// NewResultShape 创建输出结构, withVersion 为 true 时首个字段为 schema_version
+func NewResultShape(groups []string, withVersion bool) *ResultShape {
+	key := fmt.Sprintf("%v|%v", withVersion, groups)
+	if typ, ok := shapeTypes.Load(key); ok {
+		return &ResultShape{groups: groups, typ: typ.(reflect.Type), withVersion: withVersion}
+	}
+
+	var fields []reflect.StructField
+	if withVersion {
+		fields = append(fields, reflect.StructField{Name: "SchemaVersion", Type: reflect.TypeOf(0), Tag: `json:"schema_version"`})
+	}
+	parts := append([]string{""}, groups...)
+	for _, group := range parts {
+		var partType reflect.Type
+		if group == "" {
+			partType = reflect.TypeOf(ResultBase{})
+		} else {
+			partType = reflect.TypeOf(fieldGroupParts[group](&CheckInfo{}, CheckResult{}))
+		}
+		fields = append(fields, reflect.StructField{Name: partType.Name(), Type: partType, Anonymous: true})
+	}
+	typ := reflect.StructOf(fields)
+	shapeTypes.Store(key, typ)
+	return &ResultShape{groups: groups, typ: typ, withVersion: withVersion}
+}
+
+// Build 根据目标信息和分析结果构造一条输出数据
+func (s *ResultShape) Build(info *CheckInfo, result CheckResult) interface{} {
+	value := reflect.New(s.typ).Elem()
+	index := 0
+	if s.withVersion {
+		value.Field(index).SetInt(ResultSchemaVersion)
+		index++
+	}
+	value.Field(index).Set(reflect.ValueOf(ResultBase{
+		RAW: result.RAW, FMT: result.FMT, Unicode: result.Unicode, Wildcard: result.Wildcard,
+		Registrable: result.Registrable, Subdomain: result.Subdomain, Scheme: result.Scheme, Port: result.Port,
+		Path: result.Path, Status: result.Status, Error: result.Error, QueryFailed: result.QueryFailed,
+		IsRange: result.IsRange, Extra: result.Extra,
+	}))
+	for _, group := range s.groups {
+		index++
+		value.Field(index).Set(reflect.ValueOf(fieldGroupParts[group](info, result)))
+	}
+	return value.Addr().Interface()
+}
@@ -0,0 +1,47 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectFieldGroups(t *testing.T) {
+	groups, err := SelectFieldGroups(2, []string{"dns", "timing"}, []string{"evidence"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{FieldGroupVerdict, FieldGroupDNS, FieldGroupTiming}
+	if !reflect.DeepEqual(groups, want) {
+		t.Fatalf("got %v, want %v", groups, want)
+	}
+	if _, err := SelectFieldGroups(2, []string{"whois"}, nil); err == nil {
+		t.Fatal("expected error for unknown group")
+	}
+}
+
+func TestResultShape(t *testing.T) {
+	info := &CheckInfo{RAW: "a.com", FMT: "a.com", A: []string{"1.1.1.1"}}
+	result := CheckResult{RAW: "a.com", FMT: "a.com", Status: StatusOK, IsCdn: true, CdnCompany: "Cloudflare"}
+
+	shape := NewResultShape([]string{FieldGroupVerdict, FieldGroupDNS}, true)
+	data, err := json.Marshal(shape.Build(info, result))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	for _, key := range []string{`"schema_version":1`, `"fmt":"a.com"`, `"is_cdn":true`, `"A":["1.1.1.1"]`} {
+		if !strings.Contains(text, key) {
+			t.Errorf("missing %s in %s", key, text)
+		}
+	}
+	for _, key := range []string{`"evidence"`, `"Ipv4Locate"`, `"timing"`} {
+		if strings.Contains(text, key) {
+			t.Errorf("unexpected %s in %s", key, text)
+		}
+	}
+	if !strings.HasPrefix(text, `{"schema_version":1,"raw":"a.com"`) {
+		t.Errorf("unexpected field order: %s", text)
+	}
+}
@@ -0,0 +1,458 @@
+package analyzer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/winezer0/ipinfo/pkg/asninfo"
+)
+
+// 过滤字段的值类型
+const (
+	filterString = iota
+	filterBool
+	filterNumber
+	filterList
+)
+
+// filterField 过滤表达式可用的字段, 名称与 CheckResult 的 json 字段一致
+type filterField struct {
+	kind int
+	get  func(*CheckInfo) interface{}
+}
+
+var filterFields = map[string]filterField{
+	"raw":            {filterString, func(i *CheckInfo) interface{} { return i.RAW }},
+	"fmt":            {filterString, func(i *CheckInfo) interface{} { return i.FMT }},
+	"unicode":        {filterString, func(i *CheckInfo) interface{} { return i.Unicode }},
+	"registrable":    {filterString, func(i *CheckInfo) interface{} { return i.Registrable }},
+	"subdomain":      {filterString, func(i *CheckInfo) interface{} { return i.Subdomain }},
+	"scheme":         {filterString, func(i *CheckInfo) interface{} { return i.Scheme }},
+	"port":           {filterString, func(i *CheckInfo) interface{} { return i.Port }},
+	"path":           {filterString, func(i *CheckInfo) interface{} { return i.Path }},
+	"status":         {filterString, func(i *CheckInfo) interface{} { return i.Status }},
+	"error":          {filterString, func(i *CheckInfo) interface{} { return i.Error }},
+	"cdn_company":    {filterString, func(i *CheckInfo) interface{} { return i.CdnCompany }},
+	"waf_company":    {filterString, func(i *CheckInfo) interface{} { return i.WafCompany }},
+	"cloud_company":  {filterString, func(i *CheckInfo) interface{} { return i.CloudCompany }},
+	"wildcard":       {filterBool, func(i *CheckInfo) interface{} { return i.Wildcard }},
+	"is_ipv4":        {filterBool, func(i *CheckInfo) interface{} { return i.IsIpv4 }},
+	"is_range":       {filterBool, func(i *CheckInfo) interface{} { return i.IsRange }},
+	"query_failed":   {filterBool, func(i *CheckInfo) interface{} { return i.QueryFailed }},
+	"is_cdn":         {filterBool, func(i *CheckInfo) interface{} { return i.IsCdn }},
+	"is_waf":         {filterBool, func(i *CheckInfo) interface{} { return i.IsWaf }},
+	"is_cloud":       {filterBool, func(i *CheckInfo) interface{} { return i.IsCloud }},
+	"ip_size_is_cdn": {filterBool, func(i *CheckInfo) interface{} { return i.IpSizeIsCdn }},
+	"ip_size":        {filterNumber, func(i *CheckInfo) interface{} { return float64(i.IpSize) }},
+	"a":              {filterList, func(i *CheckInfo) interface{} { return i.A }},
+	"aaaa":           {filterList, func(i *CheckInfo) interface{} { return i.AAAA }},
+	"cname":          {filterList, func(i *CheckInfo) interface{} { return i.CNAME }},
+	"ns":             {filterList, func(i *CheckInfo) interface{} { return i.NS }},
+	"mx":             {filterList, func(i *CheckInfo) interface{} { return i.MX }},
+	"txt":            {filterList, func(i *CheckInfo) interface{} { return i.TXT }},
+	"locations":      {filterList, func(i *CheckInfo) interface{} { return filterLocations(i) }},
+	"asn":            {filterList, func(i *CheckInfo) interface{} { return filterASNs(i) }},
+	"evidence":       {filterList, func(i *CheckInfo) interface{} { return filterEvidence(i) }},
+}
+
+// FilterFieldNames 返回过滤表达式可用的字段名, extra.<key> 为输入中附带的额外字段
+func FilterFieldNames() []string {
+	names := make([]string, 0, len(filterFields)+1)
+	for name := range filterFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append(names, "extra.<key>")
+}
+
+func filterLocations(info *CheckInfo) []string {
+	var values []string
+	for _, locates := range [][]map[string]string{info.Ipv4Locate, info.Ipv6Locate} {
+		for _, locate := range locates {
+			for _, value := range locate {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+func filterASNs(info *CheckInfo) []string {
+	var values []string
+	for _, asns := range [][]asninfo.ASNInfo{info.Ipv4Asn, info.Ipv6Asn} {
+		for _, asn := range asns {
+			if asn.FoundASN {
+				values = append(values, strconv.FormatUint(asn.OrganisationNumber, 10))
+			}
+		}
+	}
+	return values
+}
+
+// filterEvidence 命中依据的类型, 形如 cname, 同时提供 category:type 形式, 如 cdn:cname
+func filterEvidence(info *CheckInfo) []string {
+	var values []string
+	for _, evidence := range info.Evidence {
+		values = append(values, evidence.Type, evidence.Category+":"+evidence.Type)
+	}
+	return values
+}
+
+// Filter 编译后的结果过滤表达式
+type Filter struct {
+	expr string
+	root filterNode
+}
+
+// ParseFilter 解析过滤表达式, 如 is_cloud && cloud_company != "aliyun"
+func ParseFilter(expr string) (*Filter, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", expr, err)
+	}
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q at position %d", p.peek().text, p.peek().pos)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", expr, err)
+	}
+	return &Filter{expr: expr, root: root}, nil
+}
+
+// String 返回原始表达式
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// Match 判断合并了分析结果的 CheckInfo 是否满足过滤表达式
+func (f *Filter) Match(info *CheckInfo) bool {
+	return f.root.eval(info)
+}
+
+// MatchResult 判断分析结果是否满足过滤表达式, 合并后的结果写回 checkInfo
+func (f *Filter) MatchResult(checkInfo *CheckInfo, result CheckResult) bool {
+	return f.Match(MergeCheckResultToCheckInfo(checkInfo, result))
+}
+
+type filterNode interface {
+	eval(info *CheckInfo) bool
+}
+
+type (
+	filterOr  struct{ left, right filterNode }
+	filterAnd struct{ left, right filterNode }
+	filterNot struct{ node filterNode }
+	// filterTruth 单独的字段: 布尔值为真, 字符串和列表非空, 数字非零
+	filterTruth struct{ field string }
+	// filterCompare 字段与字面量比较
+	filterCompare struct {
+		field string
+		op    string
+		text  string  // 字面量文本, 与字符串和列表比较
+		num   float64 // 与数字比较
+		bool  bool    // 与布尔值比较
+		re    *regexp.Regexp
+	}
+)
+
+func (n filterOr) eval(info *CheckInfo) bool  { return n.left.eval(info) || n.right.eval(info) }
+func (n filterAnd) eval(info *CheckInfo) bool { return n.left.eval(info) && n.right.eval(info) }
+func (n filterNot) eval(info *CheckInfo) bool { return !n.node.eval(info) }
+
+func (n filterTruth) eval(info *CheckInfo) bool {
+	switch v := filterValue(info, n.field).(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case []string:
+		return len(v) > 0
+	}
+	return false
+}
+
+func (n filterCompare) eval(info *CheckInfo) bool {
+	switch v := filterValue(info, n.field).(type) {
+	case bool:
+		return (v == n.bool) == (n.op == "==")
+	case float64:
+		switch n.op {
+		case "==":
+			return v == n.num
+		case "!=":
+			return v != n.num
+		case ">":
+			return v > n.num
+		case ">=":
+			return v >= n.num
+		case "<":
+			return v < n.num
+		case "<=":
+			return v <= n.num
+		}
+	case string:
+		return n.matchAny([]string{v})
+	case []string:
+		return n.matchAny(v)
+	}
+	return false
+}
+
+// matchAny 列表中任意一个值满足 == 或 ~ 即为真, != 和 !~ 要求所有值都不满足
+func (n filterCompare) matchAny(values []string) bool {
+	found := false
+	for _, value := range values {
+		if n.re != nil {
+			found = n.re.MatchString(value)
+		} else {
+			found = strings.EqualFold(value, n.text)
+		}
+		if found {
+			break
+		}
+	}
+	if n.op == "!=" || n.op == "!~" {
+		return !found
+	}
+	return found
+}
+
+// filterValue 获取字段的值, extra.<key> 不存在时为空字符串
+func filterValue(info *CheckInfo, name string) interface{} {
+	if key, ok := strings.CutPrefix(name, "extra."); ok {
+		return info.Extra[key]
+	}
+	return filterFields[name].get(info)
+}
+
+// filterKind 返回字段的值类型
+func filterKind(name string) (int, bool) {
+	if strings.HasPrefix(name, "extra.") && len(name) > len("extra.") {
+		return filterString, true
+	}
+	field, ok := filterFields[name]
+	return field.kind, ok
+}
+
+// 词法单元类型
+const (
+	tokenIdent = iota
+	tokenString
+	tokenNumber
+	tokenOp
+)
+
+type filterToken struct {
+	kind int
+	text string
+	pos  int
+}
+
+// filterOps 运算符, 长的在前以便优先匹配
+var filterOps = []string{"&&", "||", "==", "!=", ">=", "<=", "!~", ">", "<", "~", "!", "(", ")"}
+
+func tokenizeFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"':
+			// 字符串使用 Go 的转义规则
+			end := i + 1
+			for end < len(expr) && expr[end] != '"' {
+				if expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			text, err := strconv.Unquote(expr[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %w", i, err)
+			}
+			tokens = append(tokens, filterToken{tokenString, text, i})
+			i = end + 1
+		case c == '-' || c == '.' || unicode.IsDigit(c):
+			end := i + 1
+			for end < len(expr) && (expr[end] == '.' || unicode.IsDigit(rune(expr[end]))) {
+				end++
+			}
+			tokens = append(tokens, filterToken{tokenNumber, expr[i:end], i})
+			i = end
+		case c == '_' || unicode.IsLetter(c):
+			end := i + 1
+			for end < len(expr) && (expr[end] == '_' || expr[end] == '.' || unicode.IsLetter(rune(expr[end])) || unicode.IsDigit(rune(expr[end]))) {
+				end++
+			}
+			tokens = append(tokens, filterToken{tokenIdent, expr[i:end], i})
+			i = end
+		default:
+			matched := false
+			for _, op := range filterOps {
+				if strings.HasPrefix(expr[i:], op) {
+					tokens = append(tokens, filterToken{tokenOp, op, i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// filterParser 递归下降解析:
+// or := and ("||" and)* ; and := unary ("&&" unary)* ; unary := "!" unary | "(" or ")" | field [op literal]
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() filterToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return filterToken{kind: -1, text: "end of expression", pos: -1}
+}
+
+func (p *filterParser) acceptOp(op string) bool {
+	if token := p.peek(); token.kind == tokenOp && token.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.acceptOp("||") {
+		var right filterNode
+		if right, err = p.parseAnd(); err == nil {
+			left = filterOr{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	for err == nil && p.acceptOp("&&") {
+		var right filterNode
+		if right, err = p.parseUnary(); err == nil {
+			left = filterAnd{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.acceptOp("!") {
+		node, err := p.parseUnary()
+		return filterNot{node}, err
+	}
+	if p.acceptOp("(") {
+		node, err := p.parseOr()
+		if err == nil && !p.acceptOp(")") {
+			err = fmt.Errorf("missing ) at position %d", p.peek().pos)
+		}
+		return node, err
+	}
+
+	token := p.peek()
+	if token.kind != tokenIdent {
+		return nil, fmt.Errorf("expected field name, got %q at position %d", token.text, token.pos)
+	}
+	p.pos++
+	// 字段名不区分大小写, extra 中的键保持原样
+	name := strings.ToLower(token.text)
+	if strings.HasPrefix(name, "extra.") {
+		name = "extra." + token.text[len("extra."):]
+	}
+	kind, ok := filterKind(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q, supported: %s", token.text, strings.Join(FilterFieldNames(), ","))
+	}
+
+	op := p.peek()
+	if op.kind != tokenOp || !isCompareOp(op.text) {
+		return filterTruth{field: name}, nil
+	}
+	p.pos++
+	return p.parseCompare(name, kind, op)
+}
+
+func isCompareOp(op string) bool {
+	switch op {
+	case "==", "!=", ">", ">=", "<", "<=", "~", "!~":
+		return true
+	}
+	return false
+}
+
+// parseCompare 解析比较的字面量并检查运算符是否适用于字段类型
+func (p *filterParser) parseCompare(name string, kind int, op filterToken) (filterNode, error) {
+	literal := p.peek()
+	if literal.kind != tokenString && literal.kind != tokenNumber && literal.kind != tokenIdent {
+		return nil, fmt.Errorf("expected value after %s, got %q at position %d", op.text, literal.text, literal.pos)
+	}
+	p.pos++
+
+	node := filterCompare{field: name, op: op.text, text: literal.text}
+	if name == "asn" {
+		// ASN 可以写作 13335 或 AS13335
+		node.text = strings.TrimPrefix(strings.ToUpper(node.text), "AS")
+	}
+	invalidOp := fmt.Errorf("operator %s does not apply to field %s", op.text, name)
+	switch kind {
+	case filterBool:
+		if op.text != "==" && op.text != "!=" {
+			return nil, invalidOp
+		}
+		value, err := strconv.ParseBool(literal.text)
+		if err != nil || literal.kind == tokenString {
+			return nil, fmt.Errorf("field %s expects true or false, got %q", name, literal.text)
+		}
+		node.bool = value
+	case filterNumber:
+		if op.text == "~" || op.text == "!~" {
+			return nil, invalidOp
+		}
+		value, err := strconv.ParseFloat(literal.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("field %s expects a number, got %q", name, literal.text)
+		}
+		node.num = value
+	default:
+		switch op.text {
+		case "~", "!~":
+			// 正则匹配不区分大小写
+			re, err := regexp.Compile("(?i)" + literal.text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regexp %q: %w", literal.text, err)
+			}
+			node.re = re
+		case "==", "!=":
+		default:
+			return nil, invalidOp
+		}
+		if literal.kind == tokenIdent {
+			return nil, fmt.Errorf("field %s expects a quoted string, got %s", name, literal.text)
+		}
+	}
+	return node, nil
+}
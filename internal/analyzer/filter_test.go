@@ -0,0 +1,53 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/winezer0/ipinfo/pkg/asninfo"
+)
+
+func TestFilter(t *testing.T) {
+	info := &CheckInfo{
+		FMT:     "www.example.com",
+		Status:  StatusOK,
+		A:       []string{"1.1.1.1", "1.1.1.2"},
+		CNAME:   []string{"e1.a.akamaiedge.net."},
+		Ipv4Asn: []asninfo.ASNInfo{{FoundASN: true, OrganisationNumber: 20940}},
+		Extra:   map[string]string{"Owner": "ops"},
+	}
+	result := CheckResult{IsCloud: true, CloudCompany: "Tencent", IpSize: 6, IsCdn: true, CdnCompany: "Akamai",
+		Evidence: []MatchEvidence{{Category: "cdn", Company: "Akamai", Type: EvidenceCNAME}}}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`is_cloud && cloud_company != "aliyun"`, true},
+		{`is_cloud && cloud_company == "TENCENT"`, true},
+		{`ip_size > 5`, true},
+		{`ip_size <= 5`, false},
+		{`cname ~ "akamai"`, true},
+		{`cname !~ "akamai"`, false},
+		{`a == "1.1.1.2" && !is_waf`, true},
+		{`is_waf || (is_cdn && evidence == "cdn:cname")`, true},
+		{`asn == "AS20940" || asn == 13335`, true},
+		{`is_cdn == false`, false},
+		{`extra.Owner == "ops" && txt`, false},
+		{`status == "ok" && !mx`, true},
+	}
+	for _, tt := range tests {
+		filter, err := ParseFilter(tt.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.expr, err)
+		}
+		if got := filter.MatchResult(info, result); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{`is_cdn &&`, `unknown == 1`, `ip_size ~ "1"`, `is_cdn == "yes"`, `cname ~ "("`, `(is_cdn`, `fmt == example`} {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("%s: expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package checkpoint
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+	"github.com/winezer0/cdninfo/pkg/fileutils"
+	"github.com/winezer0/xutils/logging"
+)
+
+// StateVersion 状态文件格式版本
+const StateVersion = 1
+
+// OutputMulti 使用 --out 写入多个输出时的输出类型, Output 为逗号连接的全部输出配置
+const OutputMulti = "multi"
+
+// Header 状态文件首行，记录本次扫描的输入和输出设置，用于恢复时的一致性检查
+type Header struct {
+	Version          int    `json:"version"`
+	InputType        string `json:"input_type"`
+	Input            string `json:"input"`
+	InputFingerprint string `json:"input_fingerprint"`
+	OutputType       string `json:"output_type"`
+	Output           string `json:"output"`
+	OutputLevel      int    `json:"output_level"`
+	CreatedAt        string `json:"created_at"`
+}
+
+// Entry 状态文件中的单条已完成记录
+type Entry struct {
+	RAW    string               `json:"raw"`
+	FMT    string               `json:"fmt"`
+	Result analyzer.CheckResult `json:"result"`
+	DoneAt string               `json:"done_at"`
+}
+
+// State 断点续扫状态，已完成的目标以追加方式逐行写入状态文件
+// 流水线分类阶段调用 IsDone 的同时输出阶段调用 MarkDone, 已完成目标的集合需要加锁
+type State struct {
+	Header  Header
+	Resumed bool
+
+	file   *os.File
+	writer *bufio.Writer
+	mu     sync.RWMutex
+	done   map[string]struct{}
+}
+
+// Open 打开或创建状态文件
+// 文件已存在时加载已完成目标并检查输出设置是否一致，输入变化时仅给出警告
+func Open(stateFile string, header Header) (*State, error) {
+	header.Version = StateVersion
+	state := &State{Header: header, done: make(map[string]struct{})}
+
+	if info, err := os.Stat(stateFile); err == nil && info.Size() > 0 {
+		old, err := state.load(stateFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkHeader(old, header); err != nil {
+			return nil, err
+		}
+		state.Header = *old
+		state.Resumed = true
+	}
+
+	file, err := os.OpenFile(stateFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state file: %w", err)
+	}
+	state.file = file
+	state.writer = bufio.NewWriter(file)
+
+	// 上次中断时可能留下不完整的行，补全换行避免与新记录粘连
+	if state.Resumed && !endsWithNewline(stateFile) {
+		if _, err := file.WriteString("\n"); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to write state file: %w", err)
+		}
+	}
+
+	if !state.Resumed {
+		state.Header.CreatedAt = time.Now().Format(time.RFC3339)
+		if err := state.writeLine(state.Header); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return state, nil
+}
+
+// load 读取已有状态文件，末尾因中断而不完整的行会被忽略
+func (s *State) load(stateFile string) (*Header, error) {
+	file, err := os.Open(stateFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	var header *Header
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Bytes()
+		if header == nil {
+			header = &Header{}
+			if err := json.Unmarshal(line, header); err != nil || header.Version == 0 {
+				return nil, fmt.Errorf("invalid state file header: %s", stateFile)
+			}
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			logging.Warnf("Skip broken state line %d: %v", lineNum, err)
+			continue
+		}
+		s.done[doneKey(entry.RAW, entry.FMT)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if header == nil {
+		return nil, fmt.Errorf("invalid state file header: %s", stateFile)
+	}
+	return header, nil
+}
+
+// endsWithNewline 判断文件最后一个字节是否为换行符
+func endsWithNewline(filePath string) bool {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	last := make([]byte, 1)
+	if _, err := file.Seek(-1, io.SeekEnd); err != nil {
+		return false
+	}
+	if _, err := file.Read(last); err != nil {
+		return false
+	}
+	return last[0] == '\n'
+}
+
+// checkHeader 检查恢复时的设置，输出设置不同会导致结果文件格式混乱，因此直接报错
+func checkHeader(old *Header, cur Header) error {
+	if old.Version != cur.Version {
+		return fmt.Errorf("state file version %d is not supported", old.Version)
+	}
+	if old.OutputType != cur.OutputType || old.Output != cur.Output || old.OutputLevel != cur.OutputLevel {
+		return fmt.Errorf("output settings changed since last run (type=%s output=%s level=%d)",
+			old.OutputType, old.Output, old.OutputLevel)
+	}
+	if old.OutputType != "sys" && old.OutputType != OutputMulti && !fileutils.IsRemoteOutput(old.OutputType) {
+		if _, err := os.Stat(old.Output); errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("output file of last run not found: %s", old.Output)
+		}
+	}
+	if old.InputFingerprint != cur.InputFingerprint {
+		logging.Warnf("The input [%s] has changed since last run, only targets not yet done will be scanned", cur.Input)
+	}
+	return nil
+}
+
+// doneKey 已完成目标的键, 同一原始输入可能展开为多个目标(如IP区间), 因此同时使用 RAW 和 FMT
+func doneKey(raw, fmtVal string) string {
+	return raw + "\t" + fmtVal
+}
+
+// IsDone 判断目标是否已经完成
+func (s *State) IsDone(raw, fmtVal string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.done[doneKey(raw, fmtVal)]
+	return ok
+}
+
+// DoneCount 已完成的目标数量
+func (s *State) DoneCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.done)
+}
+
+// MarkDone 记录一个已完成的目标
+func (s *State) MarkDone(raw, fmtVal string, result analyzer.CheckResult) error {
+	s.mu.Lock()
+	s.done[doneKey(raw, fmtVal)] = struct{}{}
+	s.mu.Unlock()
+	return s.writeLine(Entry{
+		RAW:    raw,
+		FMT:    fmtVal,
+		Result: result,
+		DoneAt: time.Now().Format(time.RFC3339),
+	})
+}
+
+func (s *State) writeLine(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := s.writer.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	// 每条记录都落盘到系统缓冲区，进程异常退出时不会丢失
+	return s.writer.Flush()
+}
+
+// Close 关闭状态文件
+func (s *State) Close() error {
+	if err := s.writer.Flush(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
@@ -0,0 +1,87 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+)
+
+func TestStateResume(t *testing.T) {
+	dir := t.TempDir()
+	stateFile := filepath.Join(dir, "state.jsonl")
+	header := Header{InputType: "str", InputFingerprint: "abc", OutputType: "sys", OutputLevel: 2}
+
+	state, err := Open(stateFile, header)
+	if err != nil {
+		t.Fatalf("open state failed: %v", err)
+	}
+	if state.Resumed {
+		t.Fatalf("new state should not be resumed")
+	}
+	if err := state.MarkDone("https://a.com/x", "a.com", analyzer.CheckResult{FMT: "a.com", IsCdn: true}); err != nil {
+		t.Fatalf("mark done failed: %v", err)
+	}
+	state.Close()
+
+	// 模拟中断时写入不完整的一行
+	f, _ := os.OpenFile(stateFile, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"raw":"b.c`)
+	f.Close()
+
+	state, err = Open(stateFile, header)
+	if err != nil {
+		t.Fatalf("reopen state failed: %v", err)
+	}
+	defer state.Close()
+	if !state.Resumed || !state.IsDone("https://a.com/x", "a.com") || state.IsDone("b.com", "b.com") || state.DoneCount() != 1 {
+		t.Fatalf("unexpected resumed state: resumed=%v done=%d", state.Resumed, state.DoneCount())
+	}
+
+	if err := state.MarkDone("c.com", "c.com", analyzer.CheckResult{FMT: "c.com"}); err != nil {
+		t.Fatalf("mark done failed: %v", err)
+	}
+	state.Close()
+
+	state, err = Open(stateFile, header)
+	if err != nil {
+		t.Fatalf("reopen state failed: %v", err)
+	}
+	if !state.IsDone("c.com", "c.com") || state.DoneCount() != 2 {
+		t.Fatalf("record after broken line lost: done=%d", state.DoneCount())
+	}
+
+	changed := header
+	changed.OutputLevel = 3
+	if _, err := Open(stateFile, changed); err == nil {
+		t.Fatalf("expected error when output settings changed")
+	}
+}
+
+func TestStateConcurrentIsDone(t *testing.T) {
+	state, err := Open(filepath.Join(t.TempDir(), "state.jsonl"), Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer state.Close()
+
+	// 分类阶段查询的同时输出阶段记录完成状态
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			state.IsDone("a.com", strconv.Itoa(i))
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		if err := state.MarkDone("a.com", strconv.Itoa(i), analyzer.CheckResult{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+	if state.DoneCount() != 1000 {
+		t.Fatalf("done count = %d, want 1000", state.DoneCount())
+	}
+}
@@ -0,0 +1,232 @@
+package diff
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+	"github.com/winezer0/cdninfo/pkg/maputils"
+)
+
+// 变化类型
+const (
+	TargetAdded   = "target_added"   // 新扫描中新增的目标
+	TargetRemoved = "target_removed" // 新扫描中不再存在的目标
+	StatusChanged = "status_changed" // 解析状态变化, 如 ok -> nxdomain
+	CdnAdded      = "cdn_added"      // 开始使用 CDN
+	CdnRemoved    = "cdn_removed"    // 不再使用 CDN, 源站可能暴露
+	CdnChanged    = "cdn_changed"    // 更换 CDN 厂商
+	WafAdded      = "waf_added"      // 开始使用 WAF
+	WafRemoved    = "waf_removed"    // 不再使用 WAF
+	WafChanged    = "waf_changed"    // 更换 WAF 厂商
+	CloudChanged  = "cloud_changed"  // 云厂商变化
+	IPsAdded      = "ips_added"      // 解析出新的 IP
+	ASNsAdded     = "asns_added"     // 解析出的 IP 属于新的 ASN
+	CNAMEChanged  = "cname_changed"  // CNAME 链变化
+)
+
+// ChangeTypes 全部变化类型, 同一目标的变化按此顺序输出
+var ChangeTypes = []string{TargetAdded, TargetRemoved, StatusChanged, CdnRemoved, CdnAdded, CdnChanged,
+	WafRemoved, WafAdded, WafChanged, CloudChanged, IPsAdded, ASNsAdded, CNAMEChanged}
+
+// IsChangeType 判断是否为已知的变化类型
+func IsChangeType(changeType string) bool {
+	return slices.Contains(ChangeTypes, changeType)
+}
+
+// Change 一个目标在两次扫描之间的一项变化
+type Change struct {
+	Target  string   `json:"target"`
+	Type    string   `json:"type"`
+	Old     string   `json:"old,omitempty"`
+	New     string   `json:"new,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	IPs     []string `json:"ips,omitempty"` // 不再使用 CDN/WAF 时当前解析出的 IP, 可能是源站
+}
+
+// snapshot 用于对比的目标状态
+type snapshot struct {
+	info   *analyzer.CheckInfo
+	hasDNS bool // -l 2 的结果没有DNS记录, 不对比 IP/ASN/CNAME
+	ips    []string
+	asns   []string
+}
+
+// Compare 按目标的 fmt (无效目标按 raw) 对比两次扫描的结果, 结果按目标和变化类型排序
+// 两次扫描中都使用 CDN 的目标的 IP/ASN 变化属于正常的调度, 不输出
+func Compare(oldInfos, newInfos []*analyzer.CheckInfo) []Change {
+	oldMap, newMap := snapshots(oldInfos), snapshots(newInfos)
+
+	var changes []Change
+	for key, cur := range newMap {
+		prev, ok := oldMap[key]
+		if !ok {
+			changes = append(changes, Change{Target: key, Type: TargetAdded, New: verdict(cur.info)})
+			continue
+		}
+		changes = append(changes, compareTarget(key, prev, cur)...)
+	}
+	for key, prev := range oldMap {
+		if _, ok := newMap[key]; !ok {
+			changes = append(changes, Change{Target: key, Type: TargetRemoved, Old: verdict(prev.info)})
+		}
+	}
+
+	order := make(map[string]int)
+	for i, t := range ChangeTypes {
+		order[t] = i
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Target != changes[j].Target {
+			return changes[i].Target < changes[j].Target
+		}
+		return order[changes[i].Type] < order[changes[j].Type]
+	})
+	return changes
+}
+
+func compareTarget(key string, prev, cur *snapshot) []Change {
+	var changes []Change
+	o, n := prev.info, cur.info
+
+	if o.Status != n.Status {
+		changes = append(changes, Change{Target: key, Type: StatusChanged, Old: o.Status, New: n.Status})
+	}
+	// 任一扫描解析失败时无法判断 CDN 是否变化
+	if o.Status != analyzer.StatusOK || n.Status != analyzer.StatusOK || o.QueryFailed || n.QueryFailed {
+		return changes
+	}
+
+	oldCdn, newCdn := o.IsCdn || o.IpSizeIsCdn, n.IsCdn || n.IpSizeIsCdn
+	categories := []struct {
+		added, removed, changed string
+		oldOn, newOn            bool
+		oldName, newName        string
+	}{
+		{CdnAdded, CdnRemoved, CdnChanged, oldCdn, newCdn, o.CdnCompany, n.CdnCompany},
+		{WafAdded, WafRemoved, WafChanged, o.IsWaf, n.IsWaf, o.WafCompany, n.WafCompany},
+		{CloudChanged, CloudChanged, CloudChanged, o.IsCloud, n.IsCloud, o.CloudCompany, n.CloudCompany},
+	}
+	for _, c := range categories {
+		switch {
+		case !c.oldOn && c.newOn:
+			changes = append(changes, Change{Target: key, Type: c.added, New: c.newName})
+		case c.oldOn && !c.newOn:
+			change := Change{Target: key, Type: c.removed, Old: c.oldName}
+			if c.removed != CloudChanged {
+				change.IPs = cur.ips
+			}
+			changes = append(changes, change)
+		case c.oldOn && c.newOn && c.oldName != c.newName:
+			changes = append(changes, Change{Target: key, Type: c.changed, Old: c.oldName, New: c.newName})
+		}
+	}
+
+	if !prev.hasDNS || !cur.hasDNS {
+		return changes
+	}
+	if !(oldCdn && newCdn) {
+		if added, removed := diffSets(prev.ips, cur.ips); len(added) > 0 {
+			changes = append(changes, Change{Target: key, Type: IPsAdded, Added: added, Removed: removed})
+		}
+		if added, removed := diffSets(prev.asns, cur.asns); len(added) > 0 {
+			changes = append(changes, Change{Target: key, Type: ASNsAdded, Added: added, Removed: removed})
+		}
+	}
+	// CNAME 链按顺序对比, 成员相同但顺序变化同样视为变化
+	if !slices.Equal(o.CNAME, n.CNAME) {
+		added, removed := diffSets(o.CNAME, n.CNAME)
+		changes = append(changes, Change{Target: key, Type: CNAMEChanged, Old: strings.Join(o.CNAME, " -> "), New: strings.Join(n.CNAME, " -> "),
+			Added: added, Removed: removed})
+	}
+	return changes
+}
+
+// snapshots 按目标建立索引, 同一目标出现多次时使用第一条结果
+func snapshots(infos []*analyzer.CheckInfo) map[string]*snapshot {
+	result := make(map[string]*snapshot, len(infos))
+	for _, info := range infos {
+		key := info.FMT
+		if key == "" {
+			key = info.RAW
+		}
+		if _, ok := result[key]; ok {
+			continue
+		}
+
+		s := &snapshot{info: info, ips: maputils.UniqueMergeSlicesSorted(info.A, info.AAAA)}
+		s.hasDNS = len(s.ips) > 0 || len(info.CNAME) > 0 || len(info.NS) > 0 || info.Ipv4Locate != nil || info.Ipv4Asn != nil
+		for _, asn := range append(append(info.Ipv4Asn[:0:0], info.Ipv4Asn...), info.Ipv6Asn...) {
+			if asn.FoundASN {
+				s.asns = maputils.UniqueMergeSlicesSorted(s.asns, []string{"AS" + strconv.FormatUint(asn.OrganisationNumber, 10)})
+			}
+		}
+		result[key] = s
+	}
+	return result
+}
+
+// diffSets 返回 cur 中新增和 prev 中被移除的值, 保持原有顺序
+func diffSets(prev, cur []string) (added, removed []string) {
+	prevSet, curSet := make(map[string]struct{}), make(map[string]struct{})
+	for _, v := range prev {
+		prevSet[v] = struct{}{}
+	}
+	for _, v := range cur {
+		curSet[v] = struct{}{}
+		if _, ok := prevSet[v]; !ok {
+			added = append(added, v)
+		}
+	}
+	for _, v := range prev {
+		if _, ok := curSet[v]; !ok {
+			removed = append(removed, v)
+		}
+	}
+	return added, removed
+}
+
+// verdict 目标判定结论的简短描述
+func verdict(info *analyzer.CheckInfo) string {
+	switch {
+	case info.Status != analyzer.StatusOK:
+		return info.Status
+	case info.IsCdn:
+		return "cdn:" + info.CdnCompany
+	case info.IsWaf:
+		return "waf:" + info.WafCompany
+	case info.IpSizeIsCdn:
+		return "cdn"
+	default:
+		return "no-cdn"
+	}
+}
+
+// String 变化的单行文本描述
+func (c Change) String() string {
+	var sb strings.Builder
+	sb.WriteString("[" + c.Type + "] " + c.Target)
+	if c.Old != "" || c.New != "" {
+		sb.WriteString(": " + valueOrNone(c.Old) + " => " + valueOrNone(c.New))
+	}
+	if len(c.Added) > 0 {
+		sb.WriteString(" +" + strings.Join(c.Added, " +"))
+	}
+	if len(c.Removed) > 0 {
+		sb.WriteString(" -" + strings.Join(c.Removed, " -"))
+	}
+	if len(c.IPs) > 0 {
+		sb.WriteString(" ips: " + strings.Join(c.IPs, ","))
+	}
+	return sb.String()
+}
+
+func valueOrNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
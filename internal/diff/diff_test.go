@@ -0,0 +1,74 @@
+package diff
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+	"github.com/winezer0/ipinfo/pkg/asninfo"
+)
+
+func TestCompare(t *testing.T) {
+	ok := analyzer.StatusOK
+	oldInfos := []*analyzer.CheckInfo{
+		{FMT: "a.com", Status: ok, IsCdn: true, CdnCompany: "Cloudflare", A: []string{"104.16.1.1"}, CNAME: []string{"a.cdn.cloudflare.net"}},
+		{FMT: "b.com", Status: ok, IsCdn: true, CdnCompany: "Akamai", A: []string{"2.2.2.2"}},
+		{FMT: "c.com", Status: ok, A: []string{"3.3.3.3"}, Ipv4Asn: []asninfo.ASNInfo{{FoundASN: true, OrganisationNumber: 100}}},
+		{FMT: "d.com", Status: ok, A: []string{"4.4.4.4"}},
+		{FMT: "gone.com", Status: ok},
+		{FMT: "e.com", Status: ok, A: []string{"6.6.6.6"}, CNAME: []string{"e.example.net", "e.edge.net"}},
+	}
+	newInfos := []*analyzer.CheckInfo{
+		{FMT: "a.com", Status: ok, A: []string{"1.2.3.4"}},
+		{FMT: "b.com", Status: ok, IsCdn: true, CdnCompany: "Fastly", A: []string{"9.9.9.9"}},
+		{FMT: "c.com", Status: ok, A: []string{"3.3.3.3", "5.5.5.5"}, Ipv4Asn: []asninfo.ASNInfo{{FoundASN: true, OrganisationNumber: 200}}},
+		{FMT: "d.com", Status: "nxdomain"},
+		{FMT: "new.com", Status: ok, IsWaf: true, WafCompany: "Imperva"},
+		{FMT: "e.com", Status: ok, A: []string{"6.6.6.6"}, CNAME: []string{"e.edge.net", "e.example.net"}},
+	}
+
+	want := []Change{
+		{Target: "a.com", Type: CdnRemoved, Old: "Cloudflare", IPs: []string{"1.2.3.4"}},
+		{Target: "a.com", Type: IPsAdded, Added: []string{"1.2.3.4"}, Removed: []string{"104.16.1.1"}},
+		{Target: "a.com", Type: CNAMEChanged, Old: "a.cdn.cloudflare.net", Removed: []string{"a.cdn.cloudflare.net"}},
+		{Target: "b.com", Type: CdnChanged, Old: "Akamai", New: "Fastly"},
+		{Target: "c.com", Type: IPsAdded, Added: []string{"5.5.5.5"}},
+		{Target: "c.com", Type: ASNsAdded, Added: []string{"AS200"}, Removed: []string{"AS100"}},
+		{Target: "d.com", Type: StatusChanged, Old: ok, New: "nxdomain"},
+		{Target: "e.com", Type: CNAMEChanged, Old: "e.example.net -> e.edge.net", New: "e.edge.net -> e.example.net"},
+		{Target: "gone.com", Type: TargetRemoved, Old: "no-cdn"},
+		{Target: "new.com", Type: TargetAdded, New: "waf:Imperva"},
+	}
+	if got := Compare(oldInfos, newInfos); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected changes:\n got=%+v\nwant=%+v", got, want)
+	}
+}
+
+func TestLoadResults(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"result.json":  `[{"raw":"a.com","fmt":"a.com","status":"ok","is_cdn":true,"cdn_company":"Cloudflare"}]`,
+		"result.jsonl": `{"schema_version":1,"raw":"a.com","fmt":"a.com","status":"ok","IsCdn":true,"CdnCompany":"Cloudflare","A":["1.1.1.1"]}` + "\n",
+		"level1.jsonl": `{"schema_version":1,"fmt":"a.com"}` + "\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"result.json", "result.jsonl"} {
+		infos, err := LoadResults(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(infos) != 1 || !infos[0].IsCdn || infos[0].CdnCompany != "Cloudflare" {
+			t.Errorf("%s: unexpected results %+v", name, infos)
+		}
+	}
+	if _, err := LoadResults(filepath.Join(dir, "level1.jsonl")); err == nil {
+		t.Errorf("expected error for results without verdict")
+	}
+}
@@ -0,0 +1,131 @@
+package diff
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+	"github.com/winezer0/cdninfo/internal/store"
+	"github.com/winezer0/cdninfo/pkg/fileutils"
+)
+
+// sqliteMagic SQLite 数据库文件头
+var sqliteMagic = []byte("SQLite format 3\x00")
+
+// LoadResults 读取一次扫描的结果, 支持 -O json/jsonl 的输出 (可以是 gzip/zstd 压缩) 和 -O sqlite 的数据库
+// 数据库路径可以使用 history.db#3 指定扫描编号, 默认读取最近一次扫描
+func LoadResults(source string) ([]*analyzer.CheckInfo, error) {
+	filePath, scanID := source, int64(0)
+	if i := strings.LastIndex(source, "#"); i > 0 {
+		if id, err := strconv.ParseInt(source[i+1:], 10, 64); err == nil {
+			filePath, scanID = source[:i], id
+		}
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header := make([]byte, len(sqliteMagic))
+	n, _ := io.ReadFull(file, header)
+	if bytes.Equal(header[:n], sqliteMagic) {
+		infos, _, err := store.LoadScan(filePath, scanID)
+		return infos, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	reader, err := fileutils.NewDecompressReader(file)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := decodeResults(bufio.NewReader(reader))
+	if err != nil {
+		return nil, fmt.Errorf("read results from %s failed: %w", filePath, err)
+	}
+	return infos, nil
+}
+
+// decodeResults 解析 JSON 数组或每行一个 JSON 对象的结果
+func decodeResults(reader *bufio.Reader) ([]*analyzer.CheckInfo, error) {
+	var infos []*analyzer.CheckInfo
+	decoder := json.NewDecoder(reader)
+
+	// JSON 数组逐个元素解析, 避免一次性读入大文件
+	first, err := peekNonSpace(reader)
+	if err != nil {
+		return nil, err
+	}
+	if first == '[' {
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+	}
+
+	for decoder.More() {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, err
+		}
+		info, err := decodeResult(raw)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// decodeResult 将 -l 2 的 CheckResult 或 -l 3 的 CheckInfo 转换为 CheckInfo, -l 1 的字符串结果没有判定结论, 返回错误
+func decodeResult(raw json.RawMessage) (*analyzer.CheckInfo, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("result is not an object, use -l 2 or -l 3 output for diff: %s", raw)
+	}
+
+	_, isResult := fields["is_cdn"]
+	if _, isInfo := fields["IsCdn"]; !isResult && !isInfo {
+		return nil, fmt.Errorf("result has no verdict, use -l 2 or -l 3 output for diff: %s", raw)
+	}
+
+	info := &analyzer.CheckInfo{}
+	if err := json.Unmarshal(raw, info); err != nil {
+		return nil, err
+	}
+	if isResult {
+		var result analyzer.CheckResult
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return nil, err
+		}
+		analyzer.MergeCheckResultToCheckInfo(info, result)
+		info.IsRange = result.IsRange
+	}
+	return info, nil
+}
+
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.Peek(1)
+		if err == io.EOF {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = reader.ReadByte()
+		default:
+			return b[0], nil
+		}
+	}
+}
@@ -23,16 +23,32 @@ func QueryDomainInfo(dnsConfig *querydomain.DNSQueryConfig, domainEntries []clas
 			checkInfo = PopulateDNSResult(domainEntry, result)
 		} else {
 			logging.Warnf("No DNS result for domain: %s", domainEntry.FMT)
-			checkInfo = analyzer.NewDomainCheckInfo(domainEntry.RAW, domainEntry.FMT, domainEntry.FromUrl)
+			checkInfo = newDomainCheckInfo(domainEntry)
+			checkInfo.Status = dnsquery.StatusError
+			checkInfo.Error = "no dns result"
 		}
 		checkInfos = append(checkInfos, checkInfo)
 	}
 	return checkInfos
 }
 
+// newDomainCheckInfo 根据域名条目创建 CheckInfo, 保留原始输入中解析出的各项信息
+func newDomainCheckInfo(domainEntry classify.TargetEntry) *analyzer.CheckInfo {
+	checkInfo := analyzer.NewDomainCheckInfo(domainEntry.RAW, domainEntry.FMT, domainEntry.FromUrl)
+	checkInfo.Unicode = domainEntry.Unicode
+	checkInfo.Wildcard = domainEntry.Wildcard
+	checkInfo.Registrable = domainEntry.Registrable
+	checkInfo.Subdomain = domainEntry.Subdomain
+	checkInfo.Scheme = domainEntry.Scheme
+	checkInfo.Port = domainEntry.Port
+	checkInfo.Path = domainEntry.Path
+	checkInfo.Extra = domainEntry.Extra
+	return checkInfo
+}
+
 // PopulateDNSResult 将 DNS 查询结果填充到 CheckInfo 中
 func PopulateDNSResult(domainEntry classify.TargetEntry, query *dnsquery.DNSResult) *analyzer.CheckInfo {
-	dnsResult := analyzer.NewDomainCheckInfo(domainEntry.RAW, domainEntry.FMT, domainEntry.FromUrl)
+	dnsResult := newDomainCheckInfo(domainEntry)
 
 	// 逐个复制 DNS 记录
 	dnsResult.A = append(dnsResult.A, query.A...)
@@ -42,5 +58,13 @@ func PopulateDNSResult(domainEntry classify.TargetEntry, query *dnsquery.DNSResu
 	dnsResult.MX = append(dnsResult.MX, query.MX...)
 	dnsResult.TXT = append(dnsResult.TXT, query.TXT...)
 
+	// 记录解析状态，区分不存在、解析失败和超时
+	if query.Status == "" {
+		dnsquery.UpdateStatus(query)
+	}
+	dnsResult.Status = query.Status
+	dnsResult.Error = dnsquery.StatusMessage(query.Status)
+	dnsResult.DNSError = query.Errors
+
 	return dnsResult
 }
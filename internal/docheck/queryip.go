@@ -1,36 +1,65 @@
 package docheck
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/winezer0/cdninfo/internal/analyzer"
+	"github.com/winezer0/cdninfo/pkg/progress"
 	"github.com/winezer0/ipinfo/pkg/queryip"
 	"github.com/winezer0/xutils/logging"
 )
 
 // QueryIPInfo 进行IP信息查询
 func QueryIPInfo(ipDbConfig *queryip.IPDbConfig, checkInfos []*analyzer.CheckInfo) []*analyzer.CheckInfo {
+	in := make(chan []*analyzer.CheckInfo, 1)
+	out := make(chan []*analyzer.CheckInfo, 1)
+	in <- checkInfos
+	close(in)
+
+	if err := QueryIPInfoStream(ipDbConfig, nil, in, out); err != nil {
+		logging.Fatalf("初始化数据库失败: %v", err)
+	}
+	return <-out
+}
+
+// QueryIPInfoStream 持续从 in 中读取 CheckInfo 批次进行IP信息查询，并写入 out
+// 数据库引擎在整个流程中只初始化一次，函数返回时关闭 out, stats 为 nil 时不记录查询统计
+// 初始化失败时立即返回错误, 不再读取 in, 由调用方停止上游
+func QueryIPInfoStream(ipDbConfig *queryip.IPDbConfig, stats *progress.Stats, in <-chan []*analyzer.CheckInfo, out chan<- []*analyzer.CheckInfo) error {
+	defer close(out)
+
 	// 初始化IP数据库引擎
 	ipEngines, err := queryip.InitDBEngines(ipDbConfig)
 	if err != nil {
-		logging.Fatalf("初始化数据库失败: %v", err)
+		return fmt.Errorf("init ip db engines failed: %w", err)
 	}
 	defer ipEngines.Close()
 
-	//对 checkInfos 中的A/AAAA记录进行IP信息查询，并赋值回去
-	for _, checkInfo := range checkInfos {
-		if len(checkInfo.A) > 0 || len(checkInfo.AAAA) > 0 {
-			ipInfo, err := ipEngines.QueryIPInfo(checkInfo.A, checkInfo.AAAA)
-			if err != nil {
-				logging.Warnf("查询IP信息失败: %v", err)
-			} else {
-				checkInfo.Ipv4Locate = convertIPLocationsToMap(ipInfo.IPv4Locations)
-				checkInfo.Ipv4Asn = ipInfo.IPv4AsnInfos
-				checkInfo.Ipv6Locate = convertIPLocationsToMap(ipInfo.IPv6Locations)
-				checkInfo.Ipv6Asn = ipInfo.IPv6AsnInfos
+	for checkInfos := range in {
+		start := time.Now()
+		//对 checkInfos 中的A/AAAA记录进行IP信息查询，并赋值回去
+		for _, checkInfo := range checkInfos {
+			if len(checkInfo.A) > 0 || len(checkInfo.AAAA) > 0 {
+				ipInfo, err := ipEngines.QueryIPInfo(checkInfo.A, checkInfo.AAAA)
+				stats.RecordIPLookup(err)
+				if err != nil {
+					logging.Warnf("查询IP信息失败: %v", err)
+				} else {
+					checkInfo.Ipv4Locate = convertIPLocationsToMap(ipInfo.IPv4Locations)
+					checkInfo.Ipv4Asn = ipInfo.IPv4AsnInfos
+					checkInfo.Ipv6Locate = convertIPLocationsToMap(ipInfo.IPv6Locations)
+					checkInfo.Ipv6Asn = ipInfo.IPv6AsnInfos
+				}
 			}
 		}
+		elapsed := time.Since(start).Milliseconds()
+		for _, checkInfo := range checkInfos {
+			checkInfo.Timing.BatchIPInfoMs = elapsed
+		}
+		out <- checkInfos
 	}
-
-	return checkInfos
+	return nil
 }
 
 func convertIPLocationsToMap(locations []queryip.IPLocation) []map[string]string {
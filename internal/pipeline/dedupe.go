@@ -0,0 +1,100 @@
+package pipeline
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/winezer0/cdninfo/pkg/classify"
+)
+
+// dedupeGroup 相同 FMT 的一组目标
+type dedupeGroup struct {
+	fmt       string
+	record    *Record                // 首个目标的结果快照，结果产生前为 nil
+	followers []classify.TargetEntry // 结果产生前到达的重复目标
+	elem      *list.Element          // 结果产生后在已完成列表中的位置
+}
+
+// deduper 相同 FMT 的目标只解析和分析一次，结果再分发给每个原始输入
+// 等待结果的分组一直保留, 已产生结果的分组最多保留 limit 个, 超过时淘汰最久未使用的分组
+// 被淘汰的 FMT 之后再出现时重新解析, 内存占用不随输入数量增长
+type deduper struct {
+	mu       sync.Mutex
+	groups   map[string]*dedupeGroup
+	finished *list.List // 已产生结果的分组, 最近使用的在前
+	limit    int
+}
+
+func newDeduper(limit int) *deduper {
+	return &deduper{groups: make(map[string]*dedupeGroup), finished: list.New(), limit: limit}
+}
+
+// add 登记目标，返回 true 表示是首次出现需要处理
+// 重复目标在结果已经产生时返回可直接输出的记录，否则等待 done 时分发
+func (d *deduper) add(entry classify.TargetEntry) (bool, *Record) {
+	// 无效目标没有 FMT，逐个输出
+	if entry.FMT == "" {
+		return true, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	group, ok := d.groups[entry.FMT]
+	if !ok {
+		d.groups[entry.FMT] = &dedupeGroup{fmt: entry.FMT}
+		return true, nil
+	}
+	if group.record != nil {
+		d.finished.MoveToFront(group.elem)
+		return false, fanOut(group.record, entry)
+	}
+	group.followers = append(group.followers, entry)
+	return false, nil
+}
+
+// done 记录首个目标的结果，返回分发给等待中的重复目标的记录
+// 需要在 record 输出之前调用，保证快照不受输出时修改的影响
+func (d *deduper) done(record *Record) []*Record {
+	if record.Info.FMT == "" {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	group, ok := d.groups[record.Info.FMT]
+	if !ok || group.record != nil {
+		return nil
+	}
+
+	info := *record.Info
+	group.record = &Record{Info: &info, Result: record.Result}
+	records := make([]*Record, 0, len(group.followers))
+	for _, entry := range group.followers {
+		records = append(records, fanOut(group.record, entry))
+	}
+	group.followers = nil
+
+	group.elem = d.finished.PushFront(group)
+	for d.finished.Len() > d.limit {
+		oldest := d.finished.Remove(d.finished.Back()).(*dedupeGroup)
+		delete(d.groups, oldest.fmt)
+	}
+	return records
+}
+
+// fanOut 复制结果并替换为重复目标自身的原始输入信息
+func fanOut(record *Record, entry classify.TargetEntry) *Record {
+	info := *record.Info
+	info.RAW = entry.RAW
+	info.FromUrl = entry.FromUrl
+	info.Wildcard = entry.Wildcard
+	info.Scheme, info.Port, info.Path = entry.Scheme, entry.Port, entry.Path
+	info.Extra = entry.Extra
+
+	result := record.Result
+	result.RAW = entry.RAW
+	result.Wildcard = entry.Wildcard
+	result.Scheme, result.Port, result.Path = entry.Scheme, entry.Port, entry.Path
+	result.Extra = entry.Extra
+	return &Record{Info: &info, Result: result}
+}
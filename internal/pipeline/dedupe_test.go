@@ -0,0 +1,76 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+	"github.com/winezer0/cdninfo/pkg/classify"
+)
+
+func TestDeduperFanOut(t *testing.T) {
+	d := newDeduper(DefaultDedupeCache)
+	first := classify.TargetEntry{RAW: "https://a.com/x", FMT: "a.com", FromUrl: true}
+	early := classify.TargetEntry{RAW: "a.com:8443", FMT: "a.com", Extra: map[string]string{"owner": "bob"}}
+	late := classify.TargetEntry{RAW: "*.a.com", FMT: "a.com", Wildcard: true}
+
+	if ok, _ := d.add(first); !ok {
+		t.Fatalf("first target should be processed")
+	}
+	if ok, record := d.add(early); ok || record != nil {
+		t.Fatalf("duplicate before result should wait, got %v %v", ok, record)
+	}
+
+	info := analyzer.NewDomainCheckInfo(first.RAW, first.FMT, first.FromUrl)
+	record := &Record{Info: info, Result: analyzer.CheckResult{RAW: first.RAW, FMT: first.FMT, IsCdn: true}}
+	followers := d.done(record)
+	if len(followers) != 1 || followers[0].Result.RAW != early.RAW || !followers[0].Result.IsCdn ||
+		followers[0].Info.FromUrl || followers[0].Result.Extra["owner"] != "bob" {
+		t.Fatalf("unexpected followers: %+v", followers)
+	}
+
+	// 首个结果输出时的修改不影响后续分发
+	record.Info.IsWaf = true
+	ok, lateRecord := d.add(late)
+	if ok || lateRecord == nil {
+		t.Fatalf("duplicate after result should get a record")
+	}
+	if lateRecord.Result.RAW != late.RAW || !lateRecord.Result.Wildcard || lateRecord.Info.IsWaf {
+		t.Errorf("unexpected late record: %+v %+v", lateRecord.Result, lateRecord.Info)
+	}
+
+	if ok, _ := d.add(classify.TargetEntry{RAW: "bad"}); !ok {
+		t.Errorf("invalid targets should not be deduplicated")
+	}
+}
+
+func TestDeduperEvict(t *testing.T) {
+	d := newDeduper(2)
+	finish := func(fmtVal string) {
+		info := analyzer.NewDomainCheckInfo(fmtVal, fmtVal, false)
+		d.done(&Record{Info: info, Result: analyzer.CheckResult{RAW: fmtVal, FMT: fmtVal}})
+	}
+	for _, fmtVal := range []string{"a.com", "b.com", "c.com"} {
+		if ok, _ := d.add(classify.TargetEntry{RAW: fmtVal, FMT: fmtVal}); !ok {
+			t.Fatalf("%s should be processed", fmtVal)
+		}
+	}
+
+	// 等待结果的分组不会被淘汰
+	finish("a.com")
+	finish("b.com")
+	if ok, record := d.add(classify.TargetEntry{RAW: "a.com:443", FMT: "a.com"}); ok || record == nil {
+		t.Fatalf("a.com should be reused")
+	}
+	finish("c.com")
+	if len(d.groups) != 2 || d.finished.Len() != 2 {
+		t.Fatalf("unexpected cache size: %d groups, %d finished", len(d.groups), d.finished.Len())
+	}
+
+	// b.com 最久未使用, 被淘汰后再次出现时重新解析
+	if ok, _ := d.add(classify.TargetEntry{RAW: "b.com", FMT: "b.com"}); !ok {
+		t.Errorf("evicted b.com should be processed again")
+	}
+	if ok, record := d.add(classify.TargetEntry{RAW: "a.com", FMT: "a.com"}); ok || record == nil {
+		t.Errorf("recently used a.com should be kept")
+	}
+}
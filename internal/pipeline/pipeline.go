@@ -0,0 +1,371 @@
+package pipeline
+
+import (
+	"sync"
+	"time"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+	"github.com/winezer0/cdninfo/internal/docheck"
+	"github.com/winezer0/cdninfo/pkg/classify"
+	"github.com/winezer0/cdninfo/pkg/domaininfo/querydomain"
+	"github.com/winezer0/cdninfo/pkg/inputs"
+	"github.com/winezer0/cdninfo/pkg/progress"
+	"github.com/winezer0/ipinfo/pkg/queryip"
+	"github.com/winezer0/xutils/logging"
+)
+
+const (
+	DefaultBatchSize      = 100
+	DefaultBufferSize     = 4
+	DefaultResolveWorkers = 2
+	DefaultRangeMaxSize   = 65536
+	DefaultDedupeCache    = 100000
+)
+
+// CIDR/IP区间处理方式
+const (
+	RangeModeExpand = "expand"
+	RangeModeBlock  = "block"
+)
+
+// Config 流水线配置
+type Config struct {
+	DNSConfig  *querydomain.DNSQueryConfig
+	IPDbConfig *queryip.IPDbConfig
+	CDNData    *analyzer.CDNData
+
+	BatchSize      int // 每个批次包含的目标数量
+	BufferSize     int // 各阶段之间通道可缓存的批次数量
+	ResolveWorkers int // 同时进行DNS解析的批次数量
+
+	RangeMode    string // CIDR/IP区间处理方式: expand 展开为单个IP, block 作为整体分析
+	RangeMaxSize int    // 单个区间最多展开的IP数量, 超过时作为整体分析
+
+	// NoDedupe 为 true 时相同 FMT 的目标分别解析，默认只解析一次并将结果分发给每个原始输入
+	NoDedupe bool
+	// DedupeCache 去重时保留结果的 FMT 数量, 超过时淘汰最久未使用的, 之后再出现的重复目标重新解析
+	DedupeCache int
+
+	// Skip 返回 true 的目标不再处理，用于断点续扫时跳过已完成的目标
+	Skip func(entry classify.TargetEntry) bool
+
+	// Stats 记录运行统计, 为空时不记录
+	Stats *progress.Stats
+}
+
+// Record 单个目标的完整分析结果
+type Record struct {
+	Info   *analyzer.CheckInfo
+	Result analyzer.CheckResult
+}
+
+// EmitFunc 处理单条分析结果的回调函数
+type EmitFunc func(record *Record) error
+
+// Pipeline 分阶段的流式处理器: 分类 -> 解析 -> IP信息查询 -> 分析 -> 输出
+type Pipeline struct {
+	config     *Config
+	classifier *classify.TargetClassifier
+	dedupe     *deduper
+
+	stop     chan struct{} // 出现错误后关闭, 各阶段不再读取输入和处理批次
+	stopOnce sync.Once
+}
+
+// NewPipeline 创建流水线，未设置的参数使用默认值
+func NewPipeline(config *Config) *Pipeline {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = DefaultBufferSize
+	}
+	if config.ResolveWorkers <= 0 {
+		config.ResolveWorkers = DefaultResolveWorkers
+	}
+	if config.RangeMode == "" {
+		config.RangeMode = RangeModeExpand
+	}
+	if config.RangeMaxSize <= 0 {
+		config.RangeMaxSize = DefaultRangeMaxSize
+	}
+	if config.DedupeCache <= 0 {
+		config.DedupeCache = DefaultDedupeCache
+	}
+	p := &Pipeline{config: config, classifier: classify.NewCountingClassifier(), stop: make(chan struct{})}
+	if !config.NoDedupe {
+		p.dedupe = newDeduper(config.DedupeCache)
+	}
+	return p
+}
+
+// Classifier 返回流水线使用的分类器，只保存分类数量
+func (p *Pipeline) Classifier() *classify.TargetClassifier {
+	return p.classifier
+}
+
+// Run 从 targets 中读取目标并逐批处理，每得到一条结果就调用 emit
+// IP数据库初始化失败或 emit 返回错误时停止读取输入, 已读取的批次不再解析和分析, 返回第一个错误
+// 停止后不再读取 targets, 输入方需要自行结束
+func (p *Pipeline) Run(targets <-chan inputs.Target, emit EmitFunc) error {
+	// 重复目标在结果已产生后到达时, 由分类阶段直接生成记录
+	lateRecords := make(chan *Record, p.config.BatchSize)
+	entryBatches := p.classifyStage(targets, lateRecords)
+	infoBatches := p.resolveStage(entryBatches)
+
+	ipBatches := make(chan []*analyzer.CheckInfo, p.config.BufferSize)
+	ipErrCh := make(chan error, 1)
+	go func() {
+		err := docheck.QueryIPInfoStream(p.config.IPDbConfig, p.config.Stats, infoBatches, ipBatches)
+		if err != nil {
+			// 停止上游后消费剩余的批次, 使各阶段正常结束
+			p.cancel()
+			for range infoBatches {
+			}
+		}
+		ipErrCh <- err
+	}()
+
+	recordBatches := p.analyzeStage(ipBatches)
+
+	var emitErr error
+	emitRecord := func(record *Record) {
+		if emitErr != nil {
+			return
+		}
+		p.config.Stats.RecordDone()
+		if emitErr = emit(record); emitErr != nil {
+			p.cancel()
+		}
+	}
+	for recordBatches != nil || lateRecords != nil {
+		select {
+		case records, ok := <-recordBatches:
+			if !ok {
+				recordBatches = nil
+				continue
+			}
+			for _, record := range records {
+				var followers []*Record
+				if p.dedupe != nil {
+					followers = p.dedupe.done(record)
+				}
+				emitRecord(record)
+				for _, follower := range followers {
+					emitRecord(follower)
+				}
+			}
+		case record, ok := <-lateRecords:
+			if !ok {
+				lateRecords = nil
+				continue
+			}
+			emitRecord(record)
+		}
+	}
+
+	if err := <-ipErrCh; err != nil {
+		return err
+	}
+	return emitErr
+}
+
+// cancel 停止流水线, 可以重复调用
+func (p *Pipeline) cancel() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+// stopped 判断流水线是否已经停止
+func (p *Pipeline) stopped() bool {
+	select {
+	case <-p.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// target 已分类的目标
+type target struct {
+	category string
+	entry    classify.TargetEntry
+}
+
+// classifyStage 对输入目标分类并按批次输出，无效目标同样输出，由后续阶段生成对应结果
+// 重复目标不再输出到后续阶段，结果已产生时直接生成记录发送到 lateRecords
+func (p *Pipeline) classifyStage(targets <-chan inputs.Target, lateRecords chan<- *Record) <-chan []target {
+	out := make(chan []target, p.config.BufferSize)
+	go func() {
+		defer close(out)
+		defer close(lateRecords)
+		batch := make([]target, 0, p.config.BatchSize)
+		for {
+			var input inputs.Target
+			var ok bool
+			select {
+			case <-p.stop:
+				return
+			case input, ok = <-targets:
+			}
+			if !ok {
+				break
+			}
+			category, entry := p.classifier.Add(input.Value)
+			entry.Extra = input.Extra
+			p.config.Stats.RecordTarget(category)
+			if category == classify.CategoryInvalid {
+				logging.Debugf("Invalid target: %s (%s)", entry.RAW, entry.Error)
+			}
+			for _, t := range p.expandTarget(target{category: category, entry: entry}) {
+				if p.config.Skip != nil && p.config.Skip(t.entry) {
+					p.config.Stats.RecordSkipped()
+					continue
+				}
+				if p.dedupe != nil {
+					first, record := p.dedupe.add(t.entry)
+					if !first {
+						p.config.Stats.RecordDuplicate()
+						if record != nil {
+							lateRecords <- record
+						}
+						continue
+					}
+				}
+				batch = append(batch, t)
+				if len(batch) >= p.config.BatchSize {
+					out <- batch
+					batch = make([]target, 0, p.config.BatchSize)
+				}
+			}
+		}
+		if len(batch) > 0 && !p.stopped() {
+			out <- batch
+		}
+	}()
+	return out
+}
+
+// expandTarget 按配置将 CIDR/IP 区间展开为单个IP, 区间过大时作为整体分析
+func (p *Pipeline) expandTarget(t target) []target {
+	if t.category != classify.CategoryRange || p.config.RangeMode != RangeModeExpand {
+		return []target{t}
+	}
+
+	start, end, _, _ := classify.ParseIPRange(t.entry.FMT)
+	ips, err := classify.ExpandIPRange(start, end, p.config.RangeMaxSize)
+	if err != nil {
+		logging.Warnf("Analyze range [%s] as a block: %v", t.entry.RAW, err)
+		return []target{t}
+	}
+
+	targets := make([]target, 0, len(ips))
+	for _, ip := range ips {
+		entry := t.entry
+		entry.FMT = ip
+		targets = append(targets, target{category: classify.CategoryIP, entry: entry})
+	}
+	return targets
+}
+
+// resolveStage 对批次中的域名进行DNS解析，IP和无效目标直接转换为 CheckInfo
+func (p *Pipeline) resolveStage(in <-chan []target) <-chan []*analyzer.CheckInfo {
+	out := make(chan []*analyzer.CheckInfo, p.config.BufferSize)
+	var wg sync.WaitGroup
+	for i := 0; i < p.config.ResolveWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for targets := range in {
+				// 停止后只消费剩余批次, 不再解析
+				if p.stopped() {
+					continue
+				}
+				out <- p.resolveBatch(targets)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+func (p *Pipeline) resolveBatch(targets []target) []*analyzer.CheckInfo {
+	start := time.Now()
+	var domainEntries []classify.TargetEntry
+	var checkInfos []*analyzer.CheckInfo
+	for _, t := range targets {
+		var checkInfo *analyzer.CheckInfo
+		switch t.category {
+		case classify.CategoryIP:
+			checkInfo = analyzer.NewIPCheckInfo(t.entry.RAW, t.entry.FMT, t.entry.IsIPv4, t.entry.FromUrl)
+		case classify.CategoryDomain:
+			domainEntries = append(domainEntries, t.entry)
+			continue
+		case classify.CategoryRange:
+			checkInfo = analyzer.NewRangeCheckInfo(t.entry.RAW, t.entry.FMT, t.entry.IsIPv4)
+		default:
+			checkInfo = analyzer.NewInvalidCheckInfo(t.entry.RAW, t.entry.Error)
+		}
+		checkInfo.Scheme = t.entry.Scheme
+		checkInfo.Port = t.entry.Port
+		checkInfo.Path = t.entry.Path
+		checkInfo.Extra = t.entry.Extra
+		checkInfos = append(checkInfos, checkInfo)
+	}
+	if len(domainEntries) > 0 {
+		checkInfos = append(docheck.QueryDomainInfo(p.config.DNSConfig, domainEntries), checkInfos...)
+	}
+	elapsed := time.Since(start).Milliseconds()
+	for _, checkInfo := range checkInfos {
+		checkInfo.Timing.BatchResolveMs = elapsed
+	}
+	return checkInfos
+}
+
+// analyzeStage 对批次进行 CDN CLOUD WAF 信息分析
+// 分析失败时批次中的每个目标都输出 error 状态的结果, 使重复目标的分发和断点记录照常进行
+func (p *Pipeline) analyzeStage(in <-chan []*analyzer.CheckInfo) <-chan []*Record {
+	out := make(chan []*Record, p.config.BufferSize)
+	go func() {
+		defer close(out)
+		for checkInfos := range in {
+			if p.stopped() {
+				continue
+			}
+			start := time.Now()
+			checkResults, err := analyzer.CheckCDNBatch(p.config.CDNData, checkInfos)
+			if err != nil {
+				logging.Errorf("Failed to analysis CDN info: %v", err)
+				checkResults = make([]analyzer.CheckResult, len(checkInfos))
+				for i, checkInfo := range checkInfos {
+					checkResults[i] = analyzer.NewErrorCheckResult(checkInfo, err)
+				}
+			}
+			finished := time.Now()
+			records := make([]*Record, 0, len(checkInfos))
+			for i, checkInfo := range checkInfos {
+				checkInfo.Timing.BatchAnalyzeMs = finished.Sub(start).Milliseconds()
+				checkInfo.Timing.BatchFinishedAt = finished
+				recordHits(p.config.Stats, checkResults[i])
+				records = append(records, &Record{Info: checkInfo, Result: checkResults[i]})
+			}
+			out <- records
+		}
+	}()
+	return out
+}
+
+// recordHits 记录分析命中统计
+func recordHits(stats *progress.Stats, result analyzer.CheckResult) {
+	if result.IsCdn {
+		stats.RecordHit(analyzer.CategoryCDN, result.CdnCompany)
+	}
+	if result.IsWaf {
+		stats.RecordHit(analyzer.CategoryWAF, result.WafCompany)
+	}
+	if result.IsCloud {
+		stats.RecordHit(analyzer.CategoryCloud, result.CloudCompany)
+	}
+}
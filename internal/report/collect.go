@@ -0,0 +1,72 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+)
+
+// collector 收集合并了分析结果的 CheckInfo, 报表需要全部结果才能生成
+type collector struct {
+	infos []*analyzer.CheckInfo
+}
+
+// Write 记录一条合并了分析结果的 CheckInfo
+func (c *collector) Write(item interface{}) error {
+	info, ok := item.(*analyzer.CheckInfo)
+	if !ok {
+		return fmt.Errorf("report does not support item type %T", item)
+	}
+	c.infos = append(c.infos, info)
+	return nil
+}
+
+// category 报表中的厂商类别
+type category struct {
+	key  string // 与 MatchEvidence.Category 对应
+	name string
+	get  func(*analyzer.CheckInfo) (bool, string)
+}
+
+var categories = []category{
+	{"cdn", "CDN", func(i *analyzer.CheckInfo) (bool, string) { return i.IsCdn, i.CdnCompany }},
+	{"waf", "WAF", func(i *analyzer.CheckInfo) (bool, string) { return i.IsWaf, i.WafCompany }},
+	{"cloud", "Cloud", func(i *analyzer.CheckInfo) (bool, string) { return i.IsCloud, i.CloudCompany }},
+}
+
+// categoryCounts 某个类别下各厂商的目标数量
+type categoryCounts struct {
+	name   string
+	counts map[string]int
+}
+
+// providerCounts 按类别统计各厂商的目标数量
+func providerCounts(infos []*analyzer.CheckInfo) []categoryCounts {
+	result := make([]categoryCounts, 0, len(categories))
+	for _, category := range categories {
+		counts := make(map[string]int)
+		for _, info := range infos {
+			if match, company := category.get(info); match {
+				counts[company]++
+			}
+		}
+		result = append(result, categoryCounts{name: category.name, counts: counts})
+	}
+	return result
+}
+
+// sortedByCount 按数量降序返回键, 数量相同时按名称排序
+func sortedByCount(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
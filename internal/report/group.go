@@ -0,0 +1,188 @@
+package report
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+	"github.com/winezer0/cdninfo/pkg/fileutils"
+	"github.com/winezer0/cdninfo/pkg/maputils"
+)
+
+// GroupKeys --group-by 支持的聚合方式
+var GroupKeys = []string{"provider", "asn", "ip", "registrable", "subnet"}
+
+// CheckGroupBy 检查聚合方式是否支持
+func CheckGroupBy(by string) error {
+	for _, key := range GroupKeys {
+		if key == strings.ToLower(by) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported group by: %s, supported: %s", by, strings.Join(GroupKeys, "/"))
+}
+
+// Group 按同一个键聚合的目标
+type Group struct {
+	Key       string   `json:"key"`
+	Count     int      `json:"count"`
+	Targets   []string `json:"targets"`
+	IPs       []string `json:"ips"`
+	Providers []string `json:"providers"`
+}
+
+// groupKeys 返回目标所属的分组, 一个目标可以属于多个分组, 无法分组的目标返回空
+func groupKeys(info *analyzer.CheckInfo, by string) []string {
+	switch by {
+	case "provider":
+		if providers := infoProviders(info); len(providers) > 0 {
+			return providers
+		}
+		if info.Status == analyzer.StatusOK {
+			return []string{"none"}
+		}
+		return []string{"status:" + info.Status}
+	case "asn":
+		var keys []string
+		for _, asn := range append(append(info.Ipv4Asn[:0:0], info.Ipv4Asn...), info.Ipv6Asn...) {
+			if asn.FoundASN {
+				keys = append(keys, fmt.Sprintf("AS%d", asn.OrganisationNumber))
+			}
+		}
+		return keys
+	case "ip":
+		return maputils.UniqueMergeSlicesSorted(info.A, info.AAAA)
+	case "registrable":
+		if info.Registrable != "" {
+			return []string{info.Registrable}
+		}
+	case "subnet":
+		// IPv4 按 /24, IPv6 按 /64 聚合
+		var keys []string
+		for _, ip := range maputils.UniqueMergeSlicesSorted(info.A, info.AAAA) {
+			parsed := net.ParseIP(ip)
+			if parsed == nil {
+				continue
+			}
+			mask := net.CIDRMask(64, 128)
+			if parsed.To4() != nil {
+				parsed, mask = parsed.To4(), net.CIDRMask(24, 32)
+			}
+			keys = append(keys, (&net.IPNet{IP: parsed.Mask(mask), Mask: mask}).String())
+		}
+		return keys
+	}
+	return nil
+}
+
+// infoProviders 返回目标命中的厂商, 形如 cdn:Cloudflare, 仅由IP数量判定为CDN时为 cdn:ip_size
+func infoProviders(info *analyzer.CheckInfo) []string {
+	var providers []string
+	for _, category := range categories {
+		if match, company := category.get(info); match {
+			providers = append(providers, category.key+":"+company)
+		}
+	}
+	if info.IpSizeIsCdn && !info.IsCdn {
+		providers = append(providers, "cdn:ip_size")
+	}
+	return providers
+}
+
+// stringSet 去重的字符串集合, 只追加新出现的值, 全部添加后再排序
+type stringSet struct {
+	seen  map[string]struct{}
+	items []string
+}
+
+func (s *stringSet) add(values ...string) {
+	if s.seen == nil {
+		s.seen = make(map[string]struct{})
+	}
+	for _, value := range values {
+		if _, ok := s.seen[value]; !ok {
+			s.seen[value] = struct{}{}
+			s.items = append(s.items, value)
+		}
+	}
+}
+
+// sorted 返回排序后的值
+func (s *stringSet) sorted() []string {
+	sort.Strings(s.items)
+	return s.items
+}
+
+// groupSets 聚合过程中每个分组的去重集合
+type groupSets struct {
+	targets, ips, providers stringSet
+}
+
+// GroupInfos 按指定方式聚合目标, 结果按目标数量降序排列, 分组内的目标、IP 和厂商按字符串排序
+func GroupInfos(infos []*analyzer.CheckInfo, by string) []*Group {
+	by = strings.ToLower(by)
+	groupMap := make(map[string]*groupSets)
+	for _, info := range infos {
+		target := info.FMT
+		if target == "" {
+			target = info.RAW
+		}
+		for _, key := range maputils.UniqueMergeSlicesSorted(groupKeys(info, by)) {
+			sets, ok := groupMap[key]
+			if !ok {
+				sets = &groupSets{}
+				groupMap[key] = sets
+			}
+			sets.targets.add(target)
+			sets.ips.add(info.A...)
+			sets.ips.add(info.AAAA...)
+			sets.providers.add(infoProviders(info)...)
+		}
+	}
+
+	groups := make([]*Group, 0, len(groupMap))
+	for key, sets := range groupMap {
+		groups = append(groups, &Group{
+			Key: key, Count: len(sets.targets.items),
+			Targets: sets.targets.sorted(), IPs: sets.ips.sorted(), Providers: sets.providers.sorted(),
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	return groups
+}
+
+// GroupWriter 收集全部结果, 关闭时将聚合后的分组逐个写入下一级输出器
+type GroupWriter struct {
+	collector
+	by   string
+	next fileutils.StreamWriter
+}
+
+// NewGroupWriter 创建聚合输出器, next 为实际的输出器
+func NewGroupWriter(by string, next fileutils.StreamWriter) (*GroupWriter, error) {
+	if err := CheckGroupBy(by); err != nil {
+		return nil, err
+	}
+	return &GroupWriter{by: strings.ToLower(by), next: next}, nil
+}
+
+// Close 写入聚合结果并关闭下一级输出器
+func (w *GroupWriter) Close() error {
+	var err error
+	for _, group := range GroupInfos(w.infos, w.by) {
+		if err = w.next.Write(group); err != nil {
+			break
+		}
+	}
+	if closeErr := w.next.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
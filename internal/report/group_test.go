@@ -0,0 +1,47 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+)
+
+func TestGroupInfos(t *testing.T) {
+	infos := []*analyzer.CheckInfo{
+		{RAW: "a.com", FMT: "a.com", Registrable: "a.com", Status: analyzer.StatusOK, A: []string{"1.1.1.1"}, IsWaf: true, WafCompany: "Imperva"},
+		{RAW: "www.a.com", FMT: "www.a.com", Registrable: "a.com", Status: analyzer.StatusOK, A: []string{"10.0.0.2"}},
+		{RAW: "b.com", FMT: "b.com", Registrable: "b.com", Status: analyzer.StatusOK, A: []string{"10.0.0.2", "10.0.0.3"}, IsWaf: true, WafCompany: "Imperva"},
+		{RAW: "c.com", FMT: "c.com", Status: "nxdomain"},
+	}
+
+	tests := []struct {
+		by   string
+		want map[string][]string
+	}{
+		{"provider", map[string][]string{"waf:Imperva": {"a.com", "b.com"}, "none": {"www.a.com"}, "status:nxdomain": {"c.com"}}},
+		{"ip", map[string][]string{"10.0.0.2": {"b.com", "www.a.com"}, "1.1.1.1": {"a.com"}, "10.0.0.3": {"b.com"}}},
+		{"registrable", map[string][]string{"a.com": {"a.com", "www.a.com"}, "b.com": {"b.com"}}},
+		{"subnet", map[string][]string{"10.0.0.0/24": {"b.com", "www.a.com"}, "1.1.1.0/24": {"a.com"}}},
+	}
+	for _, tt := range tests {
+		groups := GroupInfos(infos, tt.by)
+		got := make(map[string][]string)
+		for _, group := range groups {
+			got[group.Key] = group.Targets
+			if group.Count != len(group.Targets) {
+				t.Errorf("%s %s: count %d, targets %v", tt.by, group.Key, group.Count, group.Targets)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.by, got, tt.want)
+		}
+		if groups[0].Count < groups[len(groups)-1].Count {
+			t.Errorf("%s: groups not sorted by count", tt.by)
+		}
+	}
+
+	if err := CheckGroupBy("domain"); err == nil {
+		t.Error("expected error for unsupported group by")
+	}
+}
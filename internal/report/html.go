@@ -0,0 +1,121 @@
+package report
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+)
+
+//go:embed report.html
+var htmlTemplate string
+
+// HtmlReport 收集全部结果, 关闭时生成不依赖外部资源的单文件 HTML 报表
+type HtmlReport struct {
+	collector
+	filePath string
+	GroupBy  string // 非空时额外输出按该方式聚合的分组表
+}
+
+// NewHtmlReport 创建 HTML 报表输出器, 实现 fileutils.StreamWriter 接口
+func NewHtmlReport(filePath string) *HtmlReport {
+	if filePath == "" {
+		filePath = "result.html"
+	}
+	return &HtmlReport{filePath: filePath}
+}
+
+// Close 渲染报表并写入文件
+func (r *HtmlReport) Close() error {
+	file, err := os.Create(r.filePath)
+	if err != nil {
+		return err
+	}
+	if err := r.Render(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// htmlBar 分布图中的一项
+type htmlBar struct {
+	Name    string
+	Count   int
+	Percent int
+}
+
+// htmlChart 一个类别的分布图
+type htmlChart struct {
+	Name string
+	Bars []htmlBar
+}
+
+// htmlData 报表模板数据, Targets 和 RealIPs 以 JSON 形式嵌入页面供脚本渲染
+type htmlData struct {
+	Generated   string
+	Total       int
+	RealTargets int
+	Charts      []htmlChart
+	Targets     []*analyzer.CheckInfo
+	RealIPs     []*RealIP
+	RealIPText  string
+	GroupBy     string
+	Groups      []*Group
+}
+
+// Render 将报表写入 w
+func (r *HtmlReport) Render(w io.Writer) error {
+	tmpl, err := template.New("report").Parse(htmlTemplate)
+	if err != nil {
+		return err
+	}
+
+	data := htmlData{
+		Generated: time.Now().Format("2006-01-02 15:04:05"),
+		Total:     len(r.infos),
+		Targets:   r.infos,
+		RealIPs:   CollectRealIPs(r.infos),
+	}
+	if r.GroupBy != "" {
+		data.GroupBy, data.Groups = strings.ToLower(r.GroupBy), GroupInfos(r.infos, r.GroupBy)
+	}
+	if data.Targets == nil {
+		data.Targets = []*analyzer.CheckInfo{}
+	}
+
+	statusCounts := make(map[string]int)
+	for _, info := range r.infos {
+		statusCounts[info.Status]++
+		if IsRealIPInfo(info) {
+			data.RealTargets++
+		}
+	}
+	groups := append(providerCounts(r.infos), categoryCounts{name: "Status", counts: statusCounts})
+	for _, group := range groups {
+		data.Charts = append(data.Charts, newHtmlChart(group))
+	}
+
+	ips := make([]string, 0, len(data.RealIPs))
+	for _, realIP := range data.RealIPs {
+		ips = append(ips, realIP.IP)
+	}
+	data.RealIPText = strings.Join(ips, "\n")
+
+	return tmpl.Execute(w, data)
+}
+
+// newHtmlChart 按数量降序生成分布图, 比例相对于最大项
+func newHtmlChart(group categoryCounts) htmlChart {
+	chart := htmlChart{Name: group.name}
+	keys := sortedByCount(group.counts)
+	for _, key := range keys {
+		count := group.counts[key]
+		chart.Bars = append(chart.Bars, htmlBar{Name: key, Count: count, Percent: count * 100 / group.counts[keys[0]]})
+	}
+	return chart
+}
@@ -0,0 +1,42 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+)
+
+func TestHtmlReport(t *testing.T) {
+	r := NewHtmlReport("")
+	infos := []*analyzer.CheckInfo{
+		{RAW: "a.com", FMT: "a.com", Status: analyzer.StatusOK, A: []string{"1.1.1.1"}, IsCdn: true, CdnCompany: "Cloudflare",
+			Evidence: []analyzer.MatchEvidence{{Category: "cdn", Company: "Cloudflare", Type: analyzer.EvidenceIP, Value: "1.1.1.1", Rule: "1.1.1.0/24"}}},
+		{RAW: "b.com", FMT: "b.com", Status: analyzer.StatusOK, A: []string{"10.0.0.2"}},
+		{RAW: "</script><b>", Status: analyzer.StatusInvalid},
+	}
+	for _, info := range infos {
+		if err := r.Write(info); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := r.Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+
+	for _, want := range []string{"Cloudflare", "1.1.1.0/24", "10.0.0.2"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report missing %q", want)
+		}
+	}
+	if strings.Contains(page, "</script><b>") {
+		t.Errorf("target data not escaped")
+	}
+	if strings.Contains(page, "src=\"http") || strings.Contains(page, "href=\"http") {
+		t.Errorf("report should not reference external assets")
+	}
+}
@@ -0,0 +1,255 @@
+package report
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+	"github.com/winezer0/cdninfo/pkg/classify"
+	"github.com/winezer0/cdninfo/pkg/maputils"
+	"github.com/winezer0/xutils/logging"
+)
+
+// RealIP 非 CDN/WAF 目标解析出的真实 IP 及其对应的域名
+type RealIP struct {
+	IP           string   `json:"ip"`
+	IsIpv4       bool     `json:"is_ipv4"`
+	Domains      []string `json:"domains"`
+	Location     string   `json:"location"`
+	CloudCompany string   `json:"cloud_company"`
+}
+
+// IsRealIPInfo 判断目标的解析结果是否可以作为真实 IP, 使用 analyzer.IsNoCdnNoWaf 判断, 与 -n 保持一致
+// 整体分析的区间没有 A/AAAA 记录, 不会导出IP, 但与 -n 相同计入非 CDN/WAF 目标
+func IsRealIPInfo(info *analyzer.CheckInfo) bool {
+	return analyzer.IsNoCdnNoWaf(analyzer.CheckResult{Status: info.Status, QueryFailed: info.QueryFailed,
+		IsCdn: info.IsCdn, IsWaf: info.IsWaf, IpSizeIsCdn: info.IpSizeIsCdn})
+}
+
+// CollectRealIPs 汇总非 CDN/WAF 目标的 A/AAAA 记录, 按 IP 去重并记录对应的域名, 结果按 IP 排序
+func CollectRealIPs(infos []*analyzer.CheckInfo) []*RealIP {
+	ipMap := make(map[string]*RealIP)
+	domainMap := make(map[string]*stringSet)
+	for _, info := range infos {
+		if !IsRealIPInfo(info) {
+			continue
+		}
+
+		locations := make(map[string]string)
+		for _, locate := range append(append([]map[string]string{}, info.Ipv4Locate...), info.Ipv6Locate...) {
+			for ip, location := range locate {
+				locations[ip] = location
+			}
+		}
+
+		for _, ip := range maputils.UniqueMergeSlicesSorted(info.A, info.AAAA) {
+			realIP, ok := ipMap[ip]
+			if !ok {
+				parsed := net.ParseIP(ip)
+				if parsed == nil {
+					continue
+				}
+				realIP = &RealIP{IP: ip, IsIpv4: parsed.To4() != nil}
+				ipMap[ip] = realIP
+				domainMap[ip] = &stringSet{}
+			}
+			// IP 输入本身没有域名
+			if net.ParseIP(info.FMT) == nil {
+				domainMap[ip].add(info.FMT)
+			}
+			if realIP.Location == "" {
+				realIP.Location = locations[ip]
+			}
+			if realIP.CloudCompany == "" {
+				realIP.CloudCompany = info.CloudCompany
+			}
+		}
+	}
+
+	result := make([]*RealIP, 0, len(ipMap))
+	for ip, realIP := range ipMap {
+		realIP.Domains = domainMap[ip].sorted()
+		result = append(result, realIP)
+	}
+	sort.Slice(result, func(i, j int) bool { return compareIP(result[i].IP, result[j].IP) < 0 })
+	return result
+}
+
+// compareIP 按地址大小比较, IPv4 排在 IPv6 之前
+func compareIP(a, b string) int {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	if v4A, v4B := ipA.To4(), ipB.To4(); v4A != nil && v4B != nil {
+		ipA, ipB = v4A, v4B
+	} else if v4A != nil {
+		return -1
+	} else if v4B != nil {
+		return 1
+	}
+	for i := range ipA {
+		if ipA[i] != ipB[i] {
+			if ipA[i] < ipB[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// RealIPFormats 真实 IP 导出支持的格式
+var RealIPFormats = []string{"list", "nmap", "masscan", "json"}
+
+// RealCIDR 合并后的网段及其包含的真实 IP 对应的域名
+type RealCIDR struct {
+	CIDR    string   `json:"cidr"`
+	IPs     []string `json:"ips"`
+	Domains []string `json:"domains"`
+}
+
+// CollapseRealIPs 将真实 IP 合并为最少的 CIDR, 并汇总每个网段对应的域名
+// 每个 IP 按合并结果中出现的前缀长度查找所属网段, 不需要逐个网段检查全部 IP
+func CollapseRealIPs(realIPs []*RealIP) []*RealCIDR {
+	ips := make([]string, 0, len(realIPs))
+	for _, realIP := range realIPs {
+		ips = append(ips, realIP.IP)
+	}
+
+	var result []*RealCIDR
+	cidrMap := make(map[string]int)
+	var prefixes []*net.IPNet
+	prefixSeen := make(map[string]bool)
+	for _, cidr := range classify.CollapseIPs(ips) {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		cidrMap[network.String()] = len(result)
+		result = append(result, &RealCIDR{CIDR: cidr})
+		if mask := network.Mask.String(); !prefixSeen[mask] {
+			prefixSeen[mask] = true
+			prefixes = append(prefixes, network)
+		}
+	}
+
+	domainSets := make([]stringSet, len(result))
+	for _, realIP := range realIPs {
+		ip := net.ParseIP(realIP.IP)
+		if v4 := ip.To4(); v4 != nil {
+			ip = v4
+		}
+		for _, prefix := range prefixes {
+			if len(prefix.Mask) != len(ip) {
+				continue
+			}
+			network := &net.IPNet{IP: ip.Mask(prefix.Mask), Mask: prefix.Mask}
+			if index, ok := cidrMap[network.String()]; ok {
+				result[index].IPs = append(result[index].IPs, realIP.IP)
+				domainSets[index].add(realIP.Domains...)
+				break
+			}
+		}
+	}
+	for i := range result {
+		result[i].Domains = domainSets[i].sorted()
+	}
+	return result
+}
+
+// RealIPWriter 收集全部结果, 关闭时导出去重后的非 CDN/WAF 真实 IP, 用于后续扫描
+type RealIPWriter struct {
+	collector
+	format   string
+	collapse bool
+	writer   io.Writer
+	closer   io.Closer
+}
+
+// NewRealIPWriter 创建真实 IP 导出器, collapse 为 true 时合并为 CIDR, closer 可以为空
+func NewRealIPWriter(format string, collapse bool, w io.Writer, closer io.Closer) (*RealIPWriter, error) {
+	if err := CheckRealIPFormat(format); err != nil {
+		return nil, err
+	}
+	return &RealIPWriter{format: strings.ToLower(format), collapse: collapse, writer: w, closer: closer}, nil
+}
+
+// CheckRealIPFormat 检查真实 IP 导出格式是否支持
+func CheckRealIPFormat(format string) error {
+	for _, f := range RealIPFormats {
+		if f == strings.ToLower(format) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported real ip format: %s, supported: %s", format, strings.Join(RealIPFormats, "/"))
+}
+
+// Close 按格式写入真实 IP
+func (r *RealIPWriter) Close() error {
+	err := r.write()
+	if r.closer != nil {
+		if closeErr := r.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func (r *RealIPWriter) write() error {
+	realIPs := CollectRealIPs(r.infos)
+	var realCIDRs []*RealCIDR
+	if r.collapse {
+		realCIDRs = CollapseRealIPs(realIPs)
+	}
+
+	if r.format == "json" {
+		data := struct {
+			SchemaVersion int         `json:"schema_version"`
+			IPs           []*RealIP   `json:"ips"`
+			CIDRs         []*RealCIDR `json:"cidrs,omitempty"`
+		}{analyzer.ResultSchemaVersion, realIPs, realCIDRs}
+		if data.IPs == nil {
+			data.IPs = []*RealIP{}
+		}
+		encoder := json.NewEncoder(r.writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(data)
+	}
+
+	// 每行一个目标, list 格式在目标后用制表符附加对应的域名
+	type line struct {
+		target  string
+		domains []string
+	}
+	var lines []line
+	if r.collapse {
+		for _, realCIDR := range realCIDRs {
+			lines = append(lines, line{realCIDR.CIDR, realCIDR.Domains})
+		}
+	} else {
+		for _, realIP := range realIPs {
+			lines = append(lines, line{realIP.IP, realIP.Domains})
+		}
+	}
+
+	bw := bufio.NewWriter(r.writer)
+	skipped := 0
+	for _, l := range lines {
+		switch {
+		case r.format == "list" && len(l.domains) > 0:
+			fmt.Fprintf(bw, "%s\t%s\n", l.target, strings.Join(l.domains, ","))
+		case r.format == "nmap" && strings.Contains(l.target, ":"):
+			// nmap 需要使用 -6 单独扫描 IPv6 目标
+			skipped++
+		default:
+			fmt.Fprintln(bw, l.target)
+		}
+	}
+	if skipped > 0 {
+		logging.Warnf("Skip %d IPv6 targets in nmap format, export them with the list or masscan format and scan with nmap -6", skipped)
+	}
+	return bw.Flush()
+}
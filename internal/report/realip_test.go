@@ -0,0 +1,86 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+)
+
+func TestRealIPWriter(t *testing.T) {
+	infos := []*analyzer.CheckInfo{
+		{RAW: "a.com", FMT: "a.com", Status: analyzer.StatusOK, A: []string{"1.1.1.1"}, IsCdn: true},
+		{RAW: "b.com", FMT: "b.com", Status: analyzer.StatusOK, A: []string{"10.0.0.2", "10.0.0.3"}, AAAA: []string{"2001:db8::1"}},
+		{RAW: "c.com", FMT: "c.com", Status: analyzer.StatusOK, A: []string{"10.0.0.2"}},
+		{RAW: "10.0.0.9", FMT: "10.0.0.9", Status: analyzer.StatusOK, A: []string{"10.0.0.9"}},
+	}
+
+	tests := []struct {
+		format   string
+		collapse bool
+		want     string
+	}{
+		{"list", false, "10.0.0.2\tb.com,c.com\n10.0.0.3\tb.com\n10.0.0.9\n2001:db8::1\tb.com\n"},
+		{"list", true, "10.0.0.2/31\tb.com,c.com\n10.0.0.9/32\n2001:db8::1/128\tb.com\n"},
+		{"nmap", false, "10.0.0.2\n10.0.0.3\n10.0.0.9\n"},
+		{"masscan", true, "10.0.0.2/31\n10.0.0.9/32\n2001:db8::1/128\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		writer, err := NewRealIPWriter(tt.format, tt.collapse, &buf, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, info := range infos {
+			_ = writer.Write(info)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s collapse=%v: got %q, want %q", tt.format, tt.collapse, buf.String(), tt.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	writer, _ := NewRealIPWriter("json", true, &buf, nil)
+	for _, info := range infos {
+		_ = writer.Write(info)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var data struct {
+		IPs   []RealIP   `json:"ips"`
+		CIDRs []RealCIDR `json:"cidrs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.IPs) != 4 || len(data.CIDRs) != 3 || len(data.CIDRs[0].IPs) != 2 {
+		t.Errorf("unexpected json export: %s", buf.String())
+	}
+
+	if _, err := NewRealIPWriter("xml", false, &buf, nil); err == nil {
+		t.Errorf("expected unsupported format error")
+	}
+}
+
+func TestIsRealIPInfo(t *testing.T) {
+	// 与 -n 使用的 analyzer.IsNoCdnNoWaf 判断一致, 包括整体分析的区间
+	results := []analyzer.CheckResult{
+		{FMT: "a.com", Status: analyzer.StatusOK},
+		{FMT: "b.com", Status: analyzer.StatusOK, IsCdn: true},
+		{FMT: "c.com", Status: analyzer.StatusOK, QueryFailed: true},
+		{FMT: "d.com", Status: "nxdomain"},
+		{FMT: "10.0.0.0/24", Status: analyzer.StatusOK, IsRange: true},
+		{FMT: "10.1.0.0/24", Status: analyzer.StatusOK, IsRange: true, IsWaf: true},
+	}
+	for _, result := range results {
+		info := analyzer.MergeCheckResultToCheckInfo(&analyzer.CheckInfo{FMT: result.FMT, IsRange: result.IsRange, Status: result.Status}, result)
+		if got, want := IsRealIPInfo(info), analyzer.IsNoCdnNoWaf(result); got != want {
+			t.Errorf("%s: got %v, want %v", result.FMT, got, want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package report
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+	"github.com/winezer0/cdninfo/pkg/maputils"
+)
+
+// OutputTemplate 对每条结果执行的文本模板, Header 在输出开头写入一次
+type OutputTemplate struct {
+	Header   string
+	Template *template.Template
+}
+
+// builtinTemplate 内置的命名模板
+type builtinTemplate struct {
+	header string
+	text   string
+}
+
+var builtinTemplates = map[string]builtinTemplate{
+	// hosts 文件格式, 每个解析出的IP一行, IP 输入不输出
+	"hosts": {text: `{{if not (isIP .FMT)}}{{range ips .}}{{.}} {{$.FMT}}
+{{end}}{{end}}`},
+	// 每个解析出的IP一行, 多个目标可能解析到同一IP, 需要时使用 sort -u 去重
+	"ips-only": {text: `{{range ips .}}{{.}}
+{{end}}`},
+	// Markdown 表格, 列表值使用 <br> 连接
+	"markdown-table": {
+		header: "| raw | fmt | status | cdn | waf | cloud | ips | cname |\n| --- | --- | --- | --- | --- | --- | --- | --- |\n",
+		text:   `| {{md .RAW}} | {{md .FMT}} | {{md .Status}} | {{md .CdnCompany}} | {{md .WafCompany}} | {{md .CloudCompany}} | {{md (join (ips .) "<br>")}} | {{md (join .CNAME "<br>")}} |`,
+	},
+}
+
+// BuiltinTemplateNames 返回内置模板名称
+func BuiltinTemplateNames() []string {
+	names := make([]string, 0, len(builtinTemplates))
+	for name := range builtinTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// templateFuncs 模板中可用的辅助函数
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"ips": func(info *analyzer.CheckInfo) []string {
+		return maputils.UniqueMergeSlicesSorted(info.A, info.AAAA)
+	},
+	"isIP": func(s string) bool {
+		return net.ParseIP(s) != nil
+	},
+	"md": func(s string) string {
+		return strings.NewReplacer("|", "\\|", "\r", "", "\n", " ").Replace(s)
+	},
+}
+
+// ParseOutputTemplate 解析 --output-template 参数, 依次作为内置模板名称、模板文件路径和模板内容处理
+// 模板对合并了分析结果的 *analyzer.CheckInfo 执行, 如 {{.FMT}}\t{{.CdnCompany}}
+func ParseOutputTemplate(value string) (*OutputTemplate, error) {
+	name, text, header := "inline", value, ""
+	if builtin, ok := builtinTemplates[value]; ok {
+		name, text, header = value, builtin.text, builtin.header
+	} else if info, err := os.Stat(value); err == nil && !info.IsDir() {
+		data, err := os.ReadFile(value)
+		if err != nil {
+			return nil, err
+		}
+		name, text = value, string(data)
+	} else {
+		// 命令行中的 \t \n 按转义字符处理
+		text = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(value)
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse output template failed: %w", err)
+	}
+	return &OutputTemplate{Header: header, Template: tmpl}, nil
+}
@@ -0,0 +1,45 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+)
+
+func TestParseOutputTemplate(t *testing.T) {
+	info := &analyzer.CheckInfo{RAW: "a.com", FMT: "a.com", Status: analyzer.StatusOK,
+		A: []string{"1.1.1.1", "1.0.0.1"}, CNAME: []string{"a|b.net"}, IsCdn: true, CdnCompany: "Cloudflare"}
+	ipInfo := &analyzer.CheckInfo{RAW: "2.2.2.2", FMT: "2.2.2.2", Status: analyzer.StatusOK, A: []string{"2.2.2.2"}}
+
+	tests := []struct {
+		value  string
+		info   *analyzer.CheckInfo
+		header string
+		want   string
+	}{
+		{`{{.FMT}}\t{{.CdnCompany}}`, info, "", "a.com\tCloudflare"},
+		{"hosts", info, "", "1.1.1.1 a.com\n1.0.0.1 a.com\n"},
+		{"hosts", ipInfo, "", ""},
+		{"ips-only", ipInfo, "", "2.2.2.2\n"},
+		{"markdown-table", info, "| raw | fmt | status | cdn | waf | cloud | ips | cname |\n| --- | --- | --- | --- | --- | --- | --- | --- |\n",
+			"| a.com | a.com | ok | Cloudflare |  |  | 1.1.1.1<br>1.0.0.1 | a\\|b.net |"},
+	}
+	for _, tt := range tests {
+		tmpl, err := ParseOutputTemplate(tt.value)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.value, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Template.Execute(&buf, tt.info); err != nil {
+			t.Fatalf("%s: %v", tt.value, err)
+		}
+		if buf.String() != tt.want || tmpl.Header != tt.header {
+			t.Errorf("%s: got %q header %q, want %q header %q", tt.value, buf.String(), tmpl.Header, tt.want, tt.header)
+		}
+	}
+
+	if _, err := ParseOutputTemplate("{{.FMT"); err == nil {
+		t.Errorf("expected parse error")
+	}
+}
@@ -0,0 +1,134 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/winezer0/cdninfo/pkg/fileutils"
+	"github.com/winezer0/cdninfo/pkg/maputils"
+)
+
+// XlsxReport 收集全部结果, 关闭时生成包含汇总、分类、真实IP和DNS详情工作表的 xlsx 报表
+type XlsxReport struct {
+	collector
+	filePath string
+	listSep  string
+	GroupBy  string // 非空时额外输出按该方式聚合的工作表
+}
+
+// NewXlsxReport 创建 xlsx 报表输出器, 实现 fileutils.StreamWriter 接口
+func NewXlsxReport(filePath, listSep string) *XlsxReport {
+	if filePath == "" {
+		filePath = "result.xlsx"
+	}
+	if listSep == "" {
+		listSep = fileutils.DefaultListSep
+	}
+	return &XlsxReport{filePath: filePath, listSep: listSep}
+}
+
+// Close 生成工作簿并写入文件
+func (r *XlsxReport) Close() error {
+	return r.Workbook().Save(r.filePath)
+}
+
+// Workbook 根据已收集的结果构造工作簿
+func (r *XlsxReport) Workbook() *fileutils.XlsxWorkbook {
+	wb := &fileutils.XlsxWorkbook{}
+	r.addSummarySheet(wb.AddSheet("Summary"))
+	for _, category := range categories {
+		r.addCategorySheet(wb.AddSheet(category.name), category)
+	}
+	r.addRealIPSheet(wb.AddSheet("Real IPs"))
+	r.addDNSSheet(wb.AddSheet("DNS"))
+	if r.GroupBy != "" {
+		r.addGroupSheet(wb.AddSheet("Group by " + strings.ToLower(r.GroupBy)))
+	}
+	return wb
+}
+
+// addSummarySheet 按类别和厂商统计目标数量, 并给出各状态的目标数量
+func (r *XlsxReport) addSummarySheet(sheet *fileutils.XlsxSheet) {
+	sheet.AddRow("category", "company", "count")
+
+	for _, group := range providerCounts(r.infos) {
+		for _, company := range sortedByCount(group.counts) {
+			sheet.AddRow(group.name, company, group.counts[company])
+		}
+	}
+
+	statusCounts := make(map[string]int)
+	realTargets := 0
+	for _, info := range r.infos {
+		statusCounts[info.Status]++
+		if IsRealIPInfo(info) {
+			realTargets++
+		}
+	}
+	sheet.AddRow()
+	sheet.AddRow("total", "targets", len(r.infos))
+	sheet.AddRow("total", "non-CDN/WAF targets", realTargets)
+	for _, status := range sortedByCount(statusCounts) {
+		sheet.AddRow("status", status, statusCounts[status])
+	}
+}
+
+// addCategorySheet 输出属于指定类别的目标
+func (r *XlsxReport) addCategorySheet(sheet *fileutils.XlsxSheet, category category) {
+	sheet.AddRow("raw", "fmt", "company", "status", "A", "AAAA", "CNAME", "ip_size", "evidence")
+	for _, info := range r.infos {
+		if match, company := category.get(info); match {
+			var evidence []string
+			for _, e := range info.Evidence {
+				if e.Category == category.key {
+					evidence = append(evidence, e.Type+": "+e.Value+" ("+e.Rule+")")
+				}
+			}
+			sheet.AddRow(info.RAW, info.FMT, company, info.Status, r.join(info.A), r.join(info.AAAA), r.join(info.CNAME), info.IpSize, r.join(evidence))
+		}
+	}
+}
+
+// addRealIPSheet 输出非 CDN/WAF 目标解析出的真实 IP
+func (r *XlsxReport) addRealIPSheet(sheet *fileutils.XlsxSheet) {
+	sheet.AddRow("ip", "version", "domains", "domain_count", "location", "cloud_company")
+	for _, realIP := range CollectRealIPs(r.infos) {
+		version := "IPv6"
+		if realIP.IsIpv4 {
+			version = "IPv4"
+		}
+		sheet.AddRow(realIP.IP, version, r.join(realIP.Domains), len(realIP.Domains), realIP.Location, realIP.CloudCompany)
+	}
+}
+
+// addDNSSheet 输出每个目标的DNS记录和IP信息
+func (r *XlsxReport) addDNSSheet(sheet *fileutils.XlsxSheet) {
+	sheet.AddRow("raw", "fmt", "status", "error", "query_failed", "A", "AAAA", "CNAME", "NS", "MX", "TXT", "locations", "asn")
+	for _, info := range r.infos {
+		locations := maputils.UniqueMergeSlicesSorted(
+			maputils.GetMapsValuesUnique(info.Ipv4Locate),
+			maputils.GetMapsValuesUnique(info.Ipv6Locate),
+		)
+		var asnList []string
+		for _, asn := range append(append(info.Ipv4Asn[:0:0], info.Ipv4Asn...), info.Ipv6Asn...) {
+			if asn.FoundASN {
+				asnList = append(asnList, fmt.Sprintf("AS%d", asn.OrganisationNumber))
+			}
+		}
+		sheet.AddRow(info.RAW, info.FMT, info.Status, info.Error, info.QueryFailed,
+			r.join(info.A), r.join(info.AAAA), r.join(info.CNAME), r.join(info.NS), r.join(info.MX), r.join(info.TXT),
+			r.join(locations), r.join(maputils.UniqueMergeSlicesSorted(asnList)))
+	}
+}
+
+// addGroupSheet 输出按 GroupBy 聚合的分组
+func (r *XlsxReport) addGroupSheet(sheet *fileutils.XlsxSheet) {
+	sheet.AddRow("key", "count", "targets", "ips", "providers")
+	for _, group := range GroupInfos(r.infos, r.GroupBy) {
+		sheet.AddRow(group.Key, group.Count, r.join(group.Targets), r.join(group.IPs), r.join(group.Providers))
+	}
+}
+
+func (r *XlsxReport) join(values []string) string {
+	return strings.Join(values, r.listSep)
+}
@@ -0,0 +1,68 @@
+package report
+
+import (
+	"archive/zip"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+)
+
+func TestXlsxReport(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "result.xlsx")
+	r := NewXlsxReport(filePath, "")
+	infos := []*analyzer.CheckInfo{
+		{RAW: "a.com", FMT: "a.com", Status: analyzer.StatusOK, A: []string{"1.1.1.1"}, IsCdn: true, CdnCompany: "Cloudflare"},
+		{RAW: "b.com", FMT: "b.com", Status: analyzer.StatusOK, A: []string{"10.0.0.2"},
+			Ipv4Locate: []map[string]string{{"10.0.0.2": "内网"}}},
+		{RAW: "c.com", FMT: "c.com", Status: analyzer.StatusOK, A: []string{"10.0.0.2", "10.0.0.1"}},
+		{RAW: "x<y", Status: analyzer.StatusInvalid},
+	}
+	for _, info := range infos {
+		if err := r.Write(info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	realIPs := CollectRealIPs(infos)
+	if len(realIPs) != 2 || realIPs[0].IP != "10.0.0.1" || realIPs[1].IP != "10.0.0.2" {
+		t.Fatalf("unexpected real ips: %+v", realIPs)
+	}
+	if got := strings.Join(realIPs[1].Domains, ","); got != "b.com,c.com" || realIPs[1].Location != "内网" {
+		t.Fatalf("unexpected real ip 10.0.0.2: %+v", realIPs[1])
+	}
+
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	parts := make(map[string]string)
+	for _, file := range zr.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[file.Name] = string(data)
+	}
+
+	for _, name := range []string{"Summary", "CDN", "WAF", "Cloud", "Real IPs", "DNS"} {
+		if !strings.Contains(parts["xl/workbook.xml"], `name="`+name+`"`) {
+			t.Errorf("sheet %s not found", name)
+		}
+	}
+	if !strings.Contains(parts["xl/worksheets/sheet1.xml"], "Cloudflare") {
+		t.Errorf("summary sheet missing provider: %s", parts["xl/worksheets/sheet1.xml"])
+	}
+	if !strings.Contains(parts["xl/worksheets/sheet6.xml"], "x&lt;y") {
+		t.Errorf("dns sheet not escaped: %s", parts["xl/worksheets/sheet6.xml"])
+	}
+}
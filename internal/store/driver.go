@@ -0,0 +1,7 @@
+package store
+
+// sqlite 驱动, 纯 Go 实现, 不需要启用 CGO 编译
+import _ "modernc.org/sqlite"
+
+// driverName database/sql 中注册的驱动名称
+const driverName = "sqlite"
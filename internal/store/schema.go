@@ -0,0 +1,79 @@
+package store
+
+// schemaVersion 数据库结构版本, 记录在 meta 表中, 结构变化时递增并在 migrate 中升级
+const schemaVersion = 1
+
+// schemaSQL 建表语句, 每次扫描的结果通过 scan_id 关联, 多次扫描累积在同一个数据库中
+var schemaSQL = []string{
+	`CREATE TABLE IF NOT EXISTS meta (
+		key   TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS scans (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		started_at  TEXT NOT NULL,
+		finished_at TEXT,
+		input_type  TEXT NOT NULL DEFAULT '',
+		input       TEXT NOT NULL DEFAULT '',
+		targets     INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE TABLE IF NOT EXISTS targets (
+		id             INTEGER PRIMARY KEY AUTOINCREMENT,
+		scan_id        INTEGER NOT NULL REFERENCES scans(id),
+		scanned_at     TEXT NOT NULL,
+		raw            TEXT NOT NULL,
+		fmt            TEXT NOT NULL,
+		unicode        TEXT NOT NULL DEFAULT '',
+		registrable    TEXT NOT NULL DEFAULT '',
+		subdomain      TEXT NOT NULL DEFAULT '',
+		scheme         TEXT NOT NULL DEFAULT '',
+		port           TEXT NOT NULL DEFAULT '',
+		path           TEXT NOT NULL DEFAULT '',
+		is_ipv4        INTEGER NOT NULL DEFAULT 0,
+		is_range       INTEGER NOT NULL DEFAULT 0,
+		wildcard       INTEGER NOT NULL DEFAULT 0,
+		status         TEXT NOT NULL,
+		error          TEXT NOT NULL DEFAULT '',
+		query_failed   INTEGER NOT NULL DEFAULT 0,
+		is_cdn         INTEGER NOT NULL DEFAULT 0,
+		cdn_company    TEXT NOT NULL DEFAULT '',
+		is_waf         INTEGER NOT NULL DEFAULT 0,
+		waf_company    TEXT NOT NULL DEFAULT '',
+		is_cloud       INTEGER NOT NULL DEFAULT 0,
+		cloud_company  TEXT NOT NULL DEFAULT '',
+		ip_size        INTEGER NOT NULL DEFAULT 0,
+		ip_size_is_cdn INTEGER NOT NULL DEFAULT 0,
+		extra          TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_targets_scan ON targets(scan_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_targets_fmt ON targets(fmt)`,
+	`CREATE TABLE IF NOT EXISTS dns_records (
+		target_id INTEGER NOT NULL REFERENCES targets(id),
+		type      TEXT NOT NULL,
+		value     TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_dns_records_target ON dns_records(target_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_dns_records_value ON dns_records(value)`,
+	`CREATE TABLE IF NOT EXISTS ip_locations (
+		target_id INTEGER NOT NULL REFERENCES targets(id),
+		ip        TEXT NOT NULL,
+		location  TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_ip_locations_target ON ip_locations(target_id)`,
+	`CREATE TABLE IF NOT EXISTS ip_asns (
+		target_id  INTEGER NOT NULL REFERENCES targets(id),
+		ip_version INTEGER NOT NULL,
+		asn        INTEGER NOT NULL,
+		info       TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_ip_asns_target ON ip_asns(target_id)`,
+	`CREATE TABLE IF NOT EXISTS evidence (
+		target_id INTEGER NOT NULL REFERENCES targets(id),
+		category  TEXT NOT NULL,
+		company   TEXT NOT NULL,
+		type      TEXT NOT NULL,
+		value     TEXT NOT NULL,
+		rule      TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_evidence_target ON evidence(target_id)`,
+}
@@ -0,0 +1,364 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+	"github.com/winezer0/ipinfo/pkg/asninfo"
+)
+
+// commitSize 每写入多少条结果提交一次事务
+const commitSize = 500
+
+// ScanMeta 本次扫描的输入信息
+type ScanMeta struct {
+	InputType string
+	Input     string
+}
+
+// SQLiteStore 将结果写入 SQLite 数据库的规范化表中, 每次运行对应 scans 表中的一条记录
+type SQLiteStore struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	scanID  int64
+	pending int
+	count   int
+}
+
+// OpenSQLiteStore 打开或创建数据库并开始一次扫描
+// resumed 为 true 时继续使用最近一次未完成的扫描, 不存在时开始新的扫描
+func OpenSQLiteStore(filePath string, resumed bool, meta ScanMeta) (*SQLiteStore, error) {
+	db, err := sql.Open(driverName, filePath)
+	if err != nil {
+		return nil, err
+	}
+	// 单连接写入, 避免 database is locked
+	db.SetMaxOpenConns(1)
+
+	s := &SQLiteStore{db: db}
+	if err := s.init(resumed, meta); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init sqlite store [%s] failed: %w", filePath, err)
+	}
+	return s, nil
+}
+
+// ScanID 返回本次扫描的编号
+func (s *SQLiteStore) ScanID() int64 {
+	return s.scanID
+}
+
+func (s *SQLiteStore) init(resumed bool, meta ScanMeta) error {
+	for _, stmt := range schemaSQL {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	var version string
+	err := s.db.QueryRow(`SELECT value FROM meta WHERE key = 'schema_version'`).Scan(&version)
+	switch {
+	case err == sql.ErrNoRows:
+		if _, err := s.db.Exec(`INSERT INTO meta (key, value) VALUES ('schema_version', ?)`, strconv.Itoa(schemaVersion)); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case version != strconv.Itoa(schemaVersion):
+		return fmt.Errorf("unsupported database schema version %s, expected %d", version, schemaVersion)
+	}
+
+	if resumed {
+		err := s.db.QueryRow(`SELECT id, targets FROM scans WHERE finished_at IS NULL ORDER BY id DESC LIMIT 1`).Scan(&s.scanID, &s.count)
+		if err == nil {
+			return nil
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+	}
+
+	result, err := s.db.Exec(`INSERT INTO scans (started_at, input_type, input) VALUES (?, ?, ?)`, now(), meta.InputType, meta.Input)
+	if err != nil {
+		return err
+	}
+	s.scanID, err = result.LastInsertId()
+	return err
+}
+
+// Write 写入一条合并了分析结果的 CheckInfo, 实现 fileutils.StreamWriter 接口
+func (s *SQLiteStore) Write(item interface{}) error {
+	info, ok := item.(*analyzer.CheckInfo)
+	if !ok {
+		return fmt.Errorf("sqlite store does not support item type %T", item)
+	}
+
+	if s.tx == nil {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		s.tx = tx
+	}
+	if err := s.insertTarget(info); err != nil {
+		return err
+	}
+
+	s.count++
+	s.pending++
+	if s.pending >= commitSize {
+		return s.commit()
+	}
+	return nil
+}
+
+// Sync 提交当前事务, 实现 fileutils.SyncWriter 接口, 断点续扫时在记录完成状态前提交
+func (s *SQLiteStore) Sync() error {
+	return s.commit()
+}
+
+func (s *SQLiteStore) insertTarget(info *analyzer.CheckInfo) error {
+	extra := ""
+	if len(info.Extra) > 0 {
+		data, _ := json.Marshal(info.Extra)
+		extra = string(data)
+	}
+
+	result, err := s.tx.Exec(`INSERT INTO targets (scan_id, scanned_at, raw, fmt, unicode, registrable, subdomain, scheme, port, path,
+		is_ipv4, is_range, wildcard, status, error, query_failed, is_cdn, cdn_company, is_waf, waf_company, is_cloud, cloud_company,
+		ip_size, ip_size_is_cdn, extra) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.scanID, now(), info.RAW, info.FMT, info.Unicode, info.Registrable, info.Subdomain, info.Scheme, info.Port, info.Path,
+		info.IsIpv4, info.IsRange, info.Wildcard, info.Status, info.Error, info.QueryFailed, info.IsCdn, info.CdnCompany,
+		info.IsWaf, info.WafCompany, info.IsCloud, info.CloudCompany, info.IpSize, info.IpSizeIsCdn, extra)
+	if err != nil {
+		return err
+	}
+	targetID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	records := []struct {
+		recordType string
+		values     []string
+	}{
+		{"A", info.A}, {"AAAA", info.AAAA}, {"CNAME", info.CNAME}, {"NS", info.NS}, {"MX", info.MX}, {"TXT", info.TXT},
+	}
+	for _, record := range records {
+		for _, value := range record.values {
+			if _, err := s.tx.Exec(`INSERT INTO dns_records (target_id, type, value) VALUES (?, ?, ?)`, targetID, record.recordType, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, locates := range [][]map[string]string{info.Ipv4Locate, info.Ipv6Locate} {
+		for _, locate := range locates {
+			for ip, location := range locate {
+				if _, err := s.tx.Exec(`INSERT INTO ip_locations (target_id, ip, location) VALUES (?, ?, ?)`, targetID, ip, location); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	asnVersions := []struct {
+		version int
+		rows    []asnRow
+	}{
+		{4, toASNs(info.Ipv4Asn)}, {6, toASNs(info.Ipv6Asn)},
+	}
+	for _, asns := range asnVersions {
+		for _, asn := range asns.rows {
+			if _, err := s.tx.Exec(`INSERT INTO ip_asns (target_id, ip_version, asn, info) VALUES (?, ?, ?, ?)`,
+				targetID, asns.version, int64(asn.number), asn.info); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, e := range info.Evidence {
+		if _, err := s.tx.Exec(`INSERT INTO evidence (target_id, category, company, type, value, rule) VALUES (?, ?, ?, ?, ?, ?)`,
+			targetID, e.Category, e.Company, e.Type, e.Value, e.Rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) commit() error {
+	if s.tx == nil {
+		return nil
+	}
+	err := s.tx.Commit()
+	s.tx, s.pending = nil, 0
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`UPDATE scans SET targets = ? WHERE id = ?`, s.count, s.scanID)
+	return err
+}
+
+// Close 提交剩余结果, 记录扫描结束时间并关闭数据库
+func (s *SQLiteStore) Close() error {
+	err := s.commit()
+	if err == nil {
+		_, err = s.db.Exec(`UPDATE scans SET finished_at = ?, targets = ? WHERE id = ?`, now(), s.count, s.scanID)
+	}
+	if closeErr := s.db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// toASNs 返回查询到的 ASN 号及完整信息的 JSON
+func toASNs(asnInfos []asninfo.ASNInfo) []asnRow {
+	var rows []asnRow
+	for _, asn := range asnInfos {
+		if !asn.FoundASN {
+			continue
+		}
+		data, err := json.Marshal(asn)
+		if err != nil {
+			continue
+		}
+		rows = append(rows, asnRow{number: asn.OrganisationNumber, info: string(data)})
+	}
+	return rows
+}
+
+// asnRow 写入 ip_asns 表的 ASN 信息
+type asnRow struct {
+	number uint64
+	info   string
+}
+
+func now() string {
+	return time.Now().Format(time.RFC3339)
+}
+
+// LoadScan 从数据库中读取一次扫描的结果, scanID 为 0 时读取最近一次扫描
+// 返回的 CheckInfo 包含判定结论、DNS记录、ASN 和命中依据, 用于对比不同扫描的结果
+func LoadScan(filePath string, scanID int64) ([]*analyzer.CheckInfo, int64, error) {
+	db, err := sql.Open(driverName, filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer db.Close()
+
+	if scanID == 0 {
+		if err := db.QueryRow(`SELECT COALESCE(MAX(id), 0) FROM scans`).Scan(&scanID); err != nil {
+			return nil, 0, err
+		}
+		if scanID == 0 {
+			return nil, 0, fmt.Errorf("no scan found in %s", filePath)
+		}
+	}
+
+	rows, err := db.Query(`SELECT id, raw, fmt, status, error, query_failed, is_range, is_cdn, cdn_company, is_waf, waf_company,
+		is_cloud, cloud_company, ip_size, ip_size_is_cdn FROM targets WHERE scan_id = ? ORDER BY id`, scanID)
+	if err != nil {
+		return nil, 0, err
+	}
+	infoMap := make(map[int64]*analyzer.CheckInfo)
+	var infos []*analyzer.CheckInfo
+	for rows.Next() {
+		var id int64
+		info := &analyzer.CheckInfo{}
+		if err := rows.Scan(&id, &info.RAW, &info.FMT, &info.Status, &info.Error, &info.QueryFailed, &info.IsRange,
+			&info.IsCdn, &info.CdnCompany, &info.IsWaf, &info.WafCompany, &info.IsCloud, &info.CloudCompany,
+			&info.IpSize, &info.IpSizeIsCdn); err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		infoMap[id] = info
+		infos = append(infos, info)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(infos) == 0 {
+		return nil, 0, fmt.Errorf("scan %d not found in %s", scanID, filePath)
+	}
+
+	err = queryTargetRows(db, `SELECT d.target_id, d.type, d.value FROM dns_records d JOIN targets t ON t.id = d.target_id
+		WHERE t.scan_id = ? ORDER BY d.rowid`, scanID, infoMap, func(info *analyzer.CheckInfo, values []string) {
+		switch values[0] {
+		case "A":
+			info.A = append(info.A, values[1])
+		case "AAAA":
+			info.AAAA = append(info.AAAA, values[1])
+		case "CNAME":
+			info.CNAME = append(info.CNAME, values[1])
+		case "NS":
+			info.NS = append(info.NS, values[1])
+		case "MX":
+			info.MX = append(info.MX, values[1])
+		case "TXT":
+			info.TXT = append(info.TXT, values[1])
+		}
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = queryTargetRows(db, `SELECT a.target_id, a.ip_version, a.asn FROM ip_asns a JOIN targets t ON t.id = a.target_id
+		WHERE t.scan_id = ? ORDER BY a.rowid`, scanID, infoMap, func(info *analyzer.CheckInfo, values []string) {
+		number, _ := strconv.ParseUint(values[1], 10, 64)
+		asn := asninfo.ASNInfo{FoundASN: true, OrganisationNumber: number}
+		if values[0] == "6" {
+			info.Ipv6Asn = append(info.Ipv6Asn, asn)
+		} else {
+			info.Ipv4Asn = append(info.Ipv4Asn, asn)
+		}
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = queryTargetRows(db, `SELECT e.target_id, e.category, e.company, e.type, e.value, e.rule FROM evidence e
+		JOIN targets t ON t.id = e.target_id WHERE t.scan_id = ? ORDER BY e.rowid`, scanID, infoMap, func(info *analyzer.CheckInfo, values []string) {
+		info.Evidence = append(info.Evidence, analyzer.MatchEvidence{
+			Category: values[0], Company: values[1], Type: values[2], Value: values[3], Rule: values[4],
+		})
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return infos, scanID, nil
+}
+
+// queryTargetRows 查询关联到目标的明细行, 第一列为 target_id, 其余列以字符串传给 fn
+func queryTargetRows(db *sql.DB, query string, scanID int64, infoMap map[int64]*analyzer.CheckInfo, fn func(*analyzer.CheckInfo, []string)) error {
+	rows, err := db.Query(query, scanID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	values := make([]string, len(columns)-1)
+	dest := make([]interface{}, len(columns))
+	var targetID int64
+	dest[0] = &targetID
+	for i := range values {
+		dest[i+1] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		if info, ok := infoMap[targetID]; ok {
+			fn(info, values)
+		}
+	}
+	return rows.Err()
+}
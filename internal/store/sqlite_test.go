@@ -0,0 +1,105 @@
+package store
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/winezer0/cdninfo/internal/analyzer"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "history.db")
+	infos := []*analyzer.CheckInfo{
+		{RAW: "a.com", FMT: "a.com", Status: analyzer.StatusOK, A: []string{"1.1.1.1"}, CNAME: []string{"a.cdn.net"},
+			IsCdn: true, CdnCompany: "Cloudflare", Ipv4Locate: []map[string]string{{"1.1.1.1": "美国"}},
+			Evidence: []analyzer.MatchEvidence{{Category: "cdn", Company: "Cloudflare", Type: analyzer.EvidenceIP, Value: "1.1.1.1", Rule: "1.1.1.0/24"}}},
+		{RAW: "bad", Status: analyzer.StatusInvalid, Error: "invalid target", Extra: map[string]string{"owner": "ops"}},
+	}
+
+	// 多次扫描累积在同一个数据库中, 恢复时没有未完成的扫描则开始新的扫描
+	for run, resumed := range []bool{false, false, true} {
+		s, err := OpenSQLiteStore(filePath, resumed, ScanMeta{InputType: "str", Input: "a.com,bad"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := int64(run + 1); s.ScanID() != want {
+			t.Fatalf("run %d: scan id = %d, want %d", run, s.ScanID(), want)
+		}
+		for _, info := range infos {
+			if err := s.Write(info); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := sql.Open(driverName, filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	queries := []struct {
+		query string
+		want  int
+	}{
+		{`SELECT COUNT(*) FROM scans WHERE finished_at IS NOT NULL`, 3},
+		{`SELECT COUNT(*) FROM targets`, 6},
+		{`SELECT COUNT(*) FROM targets t JOIN dns_records d ON d.target_id = t.id WHERE t.fmt = 'a.com' AND d.type = 'CNAME'`, 3},
+		{`SELECT COUNT(*) FROM ip_locations WHERE location = '美国'`, 3},
+		{`SELECT COUNT(*) FROM evidence WHERE rule = '1.1.1.0/24'`, 3},
+		{`SELECT COUNT(*) FROM targets WHERE status = 'invalid' AND extra = '{"owner":"ops"}'`, 3},
+	}
+	for _, q := range queries {
+		var got int
+		if err := db.QueryRow(q.query).Scan(&got); err != nil {
+			t.Fatalf("%s: %v", q.query, err)
+		}
+		if got != q.want {
+			t.Errorf("%s = %d, want %d", q.query, got, q.want)
+		}
+	}
+
+	loaded, scanID, err := LoadScan(filePath, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scanID != 3 || len(loaded) != 2 {
+		t.Fatalf("LoadScan returned scan %d with %d targets", scanID, len(loaded))
+	}
+	if got := loaded[0]; !got.IsCdn || got.CdnCompany != "Cloudflare" || len(got.A) != 1 || len(got.CNAME) != 1 || len(got.Evidence) != 1 {
+		t.Errorf("unexpected loaded target: %+v", got)
+	}
+	if _, _, err := LoadScan(filePath, 9); err == nil {
+		t.Errorf("expected error for missing scan")
+	}
+}
+
+func TestSQLiteStoreSync(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "history.db")
+	s, err := OpenSQLiteStore(filePath, false, ScanMeta{InputType: "str", Input: "a.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Write(&analyzer.CheckInfo{RAW: "a.com", FMT: "a.com", Status: analyzer.StatusOK}); err != nil {
+		t.Fatal(err)
+	}
+	// 断点续扫在记录完成状态前调用 Sync, 提交后其他连接可以读到结果
+	if err := s.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open(driverName, filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var got int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM targets`).Scan(&got); err != nil || got != 1 {
+		t.Fatalf("committed targets = %d (%v), want 1", got, err)
+	}
+}
@@ -2,21 +2,58 @@ package classify
 
 import (
 	"fmt"
+	"io"
+	"sync"
 )
 
 // TargetEntry 表示单个目标条目
 type TargetEntry struct {
-	RAW     string // 原始输入（如带端口或路径的字符串）
-	FMT     string // 格式化后的内容（纯 IP 或 domain）
-	IsIPv4  bool   // 是否IPV4
-	FromUrl bool   // 是否来源于URL
+	RAW         string // 原始输入（如带端口或路径的字符串）
+	FMT         string // 格式化后的内容（纯 IP 或 domain, IDN 域名为 punycode 形式）
+	Unicode     string // 域名的 Unicode 形式
+	Registrable string // 可注册域名(eTLD+1), 如 www.example.co.uk 为 example.co.uk
+	Subdomain   string // 可注册域名之前的子域名部分, 如 www
+	IsIPv4      bool   // 是否IPV4
+	FromUrl     bool   // 是否来源于URL
+	Scheme      string // URL 的协议, 如 https
+	Port        string // 端口, URL 未指定端口时为协议的默认端口
+	Path        string // URL 的路径
+	Wildcard    bool   // 是否为通配符域名(*.example.com), FMT 为去掉通配符后的基础域名
+	Error       string // 无效条目的原因
+
+	Extra map[string]string // 输入中附带的额外字段
 }
 
+// 目标分类类型
+const (
+	CategoryIP      = "IP"
+	CategoryDomain  = "Domain"
+	CategoryRange   = "Range"
+	CategoryInvalid = "InvalidEntries"
+)
+
 // TargetClassifier 分类器结构体
 type TargetClassifier struct {
 	IPEntries      []TargetEntry
 	DomainEntries  []TargetEntry
+	RangeEntries   []TargetEntry
 	InvalidEntries []string
+
+	countOnly bool // 仅统计数量，不保存条目
+	mu        sync.Mutex
+	summary   Summary
+}
+
+// Summary 分类数量统计
+type Summary struct {
+	Total         int `json:"total"`
+	IP            int `json:"ip"`
+	IPFromUrl     int `json:"ip_from_url"`
+	Domain        int `json:"domain"`
+	DomainFromUrl int `json:"domain_from_url"`
+	Wildcard      int `json:"wildcard"`
+	Range         int `json:"range"`
+	Invalid       int `json:"invalid"`
 }
 
 // NewTargetClassifier 创建新的分类器实例
@@ -24,50 +61,112 @@ func NewTargetClassifier() *TargetClassifier {
 	return &TargetClassifier{
 		IPEntries:      make([]TargetEntry, 0),
 		DomainEntries:  make([]TargetEntry, 0),
+		RangeEntries:   make([]TargetEntry, 0),
 		InvalidEntries: make([]string, 0),
 	}
 }
 
+// NewCountingClassifier 创建仅统计数量的分类器，用于流式处理时避免保存所有条目
+func NewCountingClassifier() *TargetClassifier {
+	classifier := NewTargetClassifier()
+	classifier.countOnly = true
+	return classifier
+}
+
 // Classify 对输入字符串切片进行分类
 func (tc *TargetClassifier) Classify(targets []string) {
 	for _, target := range targets {
-		category, raw, fmtVal, isURL, isIPv4 := classifyTarget(target)
-
-		switch category {
-		case "IP":
-			tc.IPEntries = append(tc.IPEntries, TargetEntry{
-				RAW:     raw,
-				FMT:     fmtVal,
-				FromUrl: isURL,
-				IsIPv4:  isIPv4,
-			})
-		case "Domain":
-			tc.DomainEntries = append(tc.DomainEntries, TargetEntry{
-				RAW:     raw,
-				FMT:     fmtVal,
-				FromUrl: isURL,
-				IsIPv4:  isIPv4,
-			})
-		case "InvalidEntries":
-			tc.InvalidEntries = append(tc.InvalidEntries, raw)
+		tc.Add(target)
+	}
+}
+
+// Add 对单个目标进行分类并追加到对应列表中，返回分类类型和条目
+func (tc *TargetClassifier) Add(target string) (string, TargetEntry) {
+	category, entry := ClassifyTarget(target)
+
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.summary.Total++
+	switch category {
+	case CategoryIP:
+		tc.summary.IP++
+		if entry.FromUrl {
+			tc.summary.IPFromUrl++
+		}
+		if !tc.countOnly {
+			tc.IPEntries = append(tc.IPEntries, entry)
+		}
+	case CategoryDomain:
+		tc.summary.Domain++
+		if entry.FromUrl {
+			tc.summary.DomainFromUrl++
+		}
+		if entry.Wildcard {
+			tc.summary.Wildcard++
+		}
+		if !tc.countOnly {
+			tc.DomainEntries = append(tc.DomainEntries, entry)
+		}
+	case CategoryRange:
+		tc.summary.Range++
+		if !tc.countOnly {
+			tc.RangeEntries = append(tc.RangeEntries, entry)
+		}
+	case CategoryInvalid:
+		tc.summary.Invalid++
+		if !tc.countOnly {
+			tc.InvalidEntries = append(tc.InvalidEntries, entry.RAW)
 		}
 	}
+	return category, entry
+}
+
+// Summary 获取分类数量统计
+func (tc *TargetClassifier) Summary() Summary {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return tc.summary
 }
 
 // Total 获取所有分类的数量总和
 func (tc *TargetClassifier) Total() int {
-	return len(tc.IPEntries) + len(tc.DomainEntries) + len(tc.InvalidEntries)
+	return tc.Summary().Total
 }
 
 // ShowSummary 打印摘要信息
-func (tc *TargetClassifier) ShowSummary() {
-	total := tc.Total()
-	fmt.Printf("Total targets: %d", total)
-	fmt.Printf("IPEntries: %d (from URL: %d)", len(tc.IPEntries), countURLs(tc.IPEntries))
-	fmt.Printf("DomainEntry: %d (from URL: %d)", len(tc.DomainEntries), countURLs(tc.DomainEntries))
-	fmt.Printf("InvalidEntries: %d", len(tc.InvalidEntries))
+func (tc *TargetClassifier) ShowSummary(w io.Writer) {
+	summary := tc.Summary()
+	fmt.Fprintf(w, "Total targets: %d\n", summary.Total)
+	fmt.Fprintf(w, "IPEntries: %d (from URL: %d)\n", summary.IP, summary.IPFromUrl)
+	fmt.Fprintf(w, "DomainEntry: %d (from URL: %d, wildcard: %d)\n", summary.Domain, summary.DomainFromUrl, summary.Wildcard)
+	fmt.Fprintf(w, "RangeEntries: %d\n", summary.Range)
+	fmt.Fprintf(w, "InvalidEntries: %d\n", summary.Invalid)
+}
+
+// ClassifyTarget 对单个目标进行分类，不保存任何状态，适用于流式处理
+func ClassifyTarget(target string) (string, TargetEntry) {
+	category, raw, fmtVal, isURL, isIPv4 := classifyTarget(target)
+	entry := TargetEntry{
+		RAW:     raw,
+		FMT:     fmtVal,
+		FromUrl: isURL,
+		IsIPv4:  isIPv4,
+	}
+	if category == CategoryIP || category == CategoryDomain {
+		entry.Scheme, entry.Port, entry.Path = parseLocation(raw)
+	}
+	switch category {
+	case CategoryDomain:
+		entry.FMT, entry.Wildcard = splitWildcard(fmtVal)
+		entry.Unicode = toUnicodeDomain(entry.FMT)
+		entry.Registrable, entry.Subdomain = SplitRegistrable(entry.FMT)
+	case CategoryInvalid:
+		entry.Error = invalidReason(raw)
+	}
+	return category, entry
 }
 
+// ClassifyTargets 对目标列表进行分类，返回分类器
 func ClassifyTargets(targets []string) *TargetClassifier {
 	classifier := NewTargetClassifier()
 	classifier.Classify(targets)
@@ -0,0 +1,64 @@
+package classify
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// wildcardPrefix 通配符域名前缀
+const wildcardPrefix = "*."
+
+// domainProfile IDNA 转换配置，允许 _dmarc 这类下划线标签
+var domainProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.StrictDomainName(false),
+)
+
+// asciiDomainRegex 校验转换后的 ASCII 域名，顶级域允许字母或 punycode 形式
+var asciiDomainRegex = regexp.MustCompile(`^([a-z0-9_-]+\.)*[a-z0-9_-]+\.([a-z]{2,}|xn--[a-z0-9-]+)$`)
+
+// normalizeDomain 统一大小写、去掉结尾的点并将 IDN 转换为 punycode，通配符前缀 *. 保留
+func normalizeDomain(domain string) (string, bool) {
+	domain = strings.TrimSuffix(strings.TrimSpace(domain), ".")
+	wildcard := strings.HasPrefix(domain, wildcardPrefix)
+	domain = strings.TrimPrefix(domain, wildcardPrefix)
+
+	ascii, err := domainProfile.ToASCII(domain)
+	if err != nil {
+		return "", false
+	}
+	ascii = strings.ToLower(ascii)
+	if !asciiDomainRegex.MatchString(ascii) {
+		return "", false
+	}
+	if wildcard {
+		ascii = wildcardPrefix + ascii
+	}
+	return ascii, true
+}
+
+// isValidDomain 判断是否是合法域名，支持 IDN、下划线标签和通配符
+func isValidDomain(domain string) bool {
+	_, ok := normalizeDomain(domain)
+	return ok
+}
+
+// splitWildcard 去掉通配符前缀，返回基础域名和是否为通配符
+func splitWildcard(domain string) (string, bool) {
+	if strings.HasPrefix(domain, wildcardPrefix) {
+		return strings.TrimPrefix(domain, wildcardPrefix), true
+	}
+	return domain, false
+}
+
+// toUnicodeDomain 返回 punycode 域名的 Unicode 形式，转换失败时返回原值
+func toUnicodeDomain(ascii string) string {
+	unicode, err := domainProfile.ToUnicode(ascii)
+	if err != nil {
+		return ascii
+	}
+	return unicode
+}
@@ -0,0 +1,39 @@
+package classify
+
+import "testing"
+
+func TestClassifyTargetDomain(t *testing.T) {
+	tests := []struct {
+		input    string
+		fmt      string
+		unicode  string
+		wildcard bool
+	}{
+		{"Example.COM.", "example.com", "example.com", false},
+		{"例子.中国", "xn--fsqu00a.xn--fiqs8s", "例子.中国", false},
+		{"xn--fsqu00a.xn--fiqs8s", "xn--fsqu00a.xn--fiqs8s", "例子.中国", false},
+		{"https://www.Müller.de:8443/path", "www.xn--mller-kva.de", "www.müller.de", false},
+		{"*.example.com", "example.com", "example.com", true},
+		{"_dmarc.example.com", "_dmarc.example.com", "_dmarc.example.com", false},
+	}
+
+	for _, tt := range tests {
+		category, entry := ClassifyTarget(tt.input)
+		if category != CategoryDomain {
+			t.Errorf("%s: category = %s, want %s", tt.input, category, CategoryDomain)
+			continue
+		}
+		if entry.FMT != tt.fmt || entry.Unicode != tt.unicode || entry.Wildcard != tt.wildcard {
+			t.Errorf("%s: got (%s, %s, %v), want (%s, %s, %v)",
+				tt.input, entry.FMT, entry.Unicode, entry.Wildcard, tt.fmt, tt.unicode, tt.wildcard)
+		}
+	}
+}
+
+func TestClassifyTargetInvalidDomain(t *testing.T) {
+	for _, input := range []string{"localhost", "a.b.1", "*.", "exa mple.com"} {
+		if category, _ := ClassifyTarget(input); category != CategoryInvalid {
+			t.Errorf("%s: category = %s, want %s", input, category, CategoryInvalid)
+		}
+	}
+}
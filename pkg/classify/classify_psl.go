@@ -0,0 +1,46 @@
+package classify
+
+import (
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// PublicSuffix 返回域名的公共后缀，只使用 PSL 中的 ICANN 部分
+// 与 tldextract 默认行为一致, cloudfront.net、github.io 这类私有后缀不作为公共后缀
+func PublicSuffix(domain string) string {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	suffix, icann := publicsuffix.PublicSuffix(domain)
+	for !icann {
+		index := strings.IndexByte(suffix, '.')
+		if index < 0 {
+			break
+		}
+		suffix, icann = publicsuffix.PublicSuffix(suffix[index+1:])
+	}
+	return suffix
+}
+
+// SplitRegistrable 将域名拆分为可注册域名(eTLD+1)和子域名部分
+// 域名本身是公共后缀时返回空的可注册域名
+func SplitRegistrable(domain string) (string, string) {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	suffix := PublicSuffix(domain)
+	if suffix == "" || len(domain) <= len(suffix) {
+		return "", ""
+	}
+
+	prefix := strings.TrimSuffix(domain[:len(domain)-len(suffix)], ".")
+	index := strings.LastIndexByte(prefix, '.')
+	registrable := prefix[index+1:] + "." + suffix
+	if index < 0 {
+		return registrable, ""
+	}
+	return registrable, prefix[:index]
+}
+
+// RegistrableDomain 返回域名的可注册域名(eTLD+1)
+func RegistrableDomain(domain string) string {
+	registrable, _ := SplitRegistrable(domain)
+	return registrable
+}
@@ -0,0 +1,27 @@
+package classify
+
+import "testing"
+
+func TestSplitRegistrable(t *testing.T) {
+	tests := []struct {
+		domain      string
+		registrable string
+		subdomain   string
+	}{
+		{"example.com", "example.com", ""},
+		{"www.example.com", "example.com", "www"},
+		{"a.b.example.co.uk", "example.co.uk", "a.b"},
+		{"d111.cloudfront.net", "cloudfront.net", "d111"},
+		{"user.github.io", "github.io", "user"},
+		{"WWW.Example.COM.", "example.com", "www"},
+		{"co.uk", "", ""},
+		{"com", "", ""},
+	}
+
+	for _, tt := range tests {
+		registrable, subdomain := SplitRegistrable(tt.domain)
+		if registrable != tt.registrable || subdomain != tt.subdomain {
+			t.Errorf("%s: got (%s, %s), want (%s, %s)", tt.domain, registrable, subdomain, tt.registrable, tt.subdomain)
+		}
+	}
+}
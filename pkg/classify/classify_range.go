@@ -0,0 +1,186 @@
+package classify
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseIPRange 解析 CIDR(10.0.0.0/24)、完整区间(1.2.3.4-1.2.3.50) 和 IPv4 简写区间(1.2.3.4-50)
+// 返回起止IP和规范化后的格式
+func ParseIPRange(target string) (net.IP, net.IP, string, bool) {
+	target = strings.TrimSpace(target)
+
+	if strings.Contains(target, "/") {
+		ip, network, err := net.ParseCIDR(target)
+		if err != nil {
+			return nil, nil, "", false
+		}
+		start := network.IP
+		end := make(net.IP, len(start))
+		for i := range start {
+			end[i] = start[i] | ^network.Mask[i]
+		}
+		if ip.To4() != nil {
+			start, end = start.To4(), end.To4()
+		}
+		return start, end, network.String(), true
+	}
+
+	parts := strings.Split(target, "-")
+	if len(parts) != 2 {
+		return nil, nil, "", false
+	}
+	start := net.ParseIP(strings.TrimSpace(parts[0]))
+	if start == nil {
+		return nil, nil, "", false
+	}
+	endStr := strings.TrimSpace(parts[1])
+	end := net.ParseIP(endStr)
+
+	// IPv4 简写形式, 仅替换最后一段
+	if end == nil && start.To4() != nil {
+		last, err := strconv.Atoi(endStr)
+		if err != nil || last < 0 || last > 255 {
+			return nil, nil, "", false
+		}
+		end = make(net.IP, net.IPv4len)
+		copy(end, start.To4())
+		end[3] = byte(last)
+	}
+	if end == nil {
+		return nil, nil, "", false
+	}
+
+	if start.To4() != nil {
+		if end.To4() == nil {
+			return nil, nil, "", false
+		}
+		start, end = start.To4(), end.To4()
+	} else if end.To4() != nil {
+		return nil, nil, "", false
+	}
+	if bytes.Compare(start, end) > 0 {
+		return nil, nil, "", false
+	}
+	return start, end, start.String() + "-" + end.String(), true
+}
+
+// RangeSize 返回区间内的IP数量
+func RangeSize(start, end net.IP) *big.Int {
+	size := new(big.Int).Sub(new(big.Int).SetBytes(end), new(big.Int).SetBytes(start))
+	return size.Add(size, big.NewInt(1))
+}
+
+// ExpandIPRange 将区间展开为IP列表，数量超过 maxSize 时返回错误
+func ExpandIPRange(start, end net.IP, maxSize int) ([]string, error) {
+	size := RangeSize(start, end)
+	if size.Cmp(big.NewInt(int64(maxSize))) > 0 {
+		return nil, fmt.Errorf("range size %s exceeds max %d", size.String(), maxSize)
+	}
+
+	ips := make([]string, 0, size.Int64())
+	current := make(net.IP, len(start))
+	copy(current, start)
+	for {
+		ips = append(ips, current.String())
+		if current.Equal(end) {
+			break
+		}
+		incrementIP(current)
+	}
+	return ips, nil
+}
+
+// incrementIP 将IP加一
+func incrementIP(ip net.IP) {
+	for i := len(ip) - 1; i >= 0; i-- {
+		ip[i]++
+		if ip[i] != 0 {
+			return
+		}
+	}
+}
+
+// CollapseIPs 将 IP 列表去重后合并为最少的 CIDR, 只合并连续且完整的网段, 不会引入列表之外的 IP
+// 与 ipaddress.collapse_addresses 的结果一致, IPv4 在前, 按网络地址排序, 无效 IP 被忽略
+func CollapseIPs(ips []string) []string {
+	var parsed []net.IP
+	for _, s := range ips {
+		ip := net.ParseIP(strings.TrimSpace(s))
+		if ip == nil {
+			continue
+		}
+		if v4 := ip.To4(); v4 != nil {
+			ip = v4
+		}
+		parsed = append(parsed, ip)
+	}
+	sort.Slice(parsed, func(i, j int) bool {
+		if len(parsed[i]) != len(parsed[j]) {
+			return len(parsed[i]) < len(parsed[j])
+		}
+		return bytes.Compare(parsed[i], parsed[j]) < 0
+	})
+
+	var cidrs []string
+	for i := 0; i < len(parsed); {
+		// 找出连续的区间
+		start, end := parsed[i], parsed[i]
+		j := i + 1
+		for ; j < len(parsed) && len(parsed[j]) == len(end); j++ {
+			next := make(net.IP, len(end))
+			copy(next, end)
+			incrementIP(next)
+			if parsed[j].Equal(end) {
+				continue
+			}
+			if !parsed[j].Equal(next) || next.IsUnspecified() {
+				break
+			}
+			end = parsed[j]
+		}
+		cidrs = append(cidrs, RangeToCIDRs(start, end)...)
+		i = j
+	}
+	return cidrs
+}
+
+// RangeToCIDRs 将起止IP区间拆分为最少的 CIDR
+func RangeToCIDRs(start, end net.IP) []string {
+	bits := len(start) * 8
+	cur := new(big.Int).SetBytes(start)
+	last := new(big.Int).SetBytes(end)
+	one := big.NewInt(1)
+
+	var cidrs []string
+	for cur.Cmp(last) <= 0 {
+		// 从最大的块开始, 块需要与当前地址对齐且不超过区间结尾
+		size := bits
+		if cur.Sign() > 0 {
+			size = int(cur.TrailingZeroBits())
+			if size > bits {
+				size = bits
+			}
+		}
+		for size > 0 {
+			blockEnd := new(big.Int).Lsh(one, uint(size))
+			blockEnd.Add(blockEnd, cur).Sub(blockEnd, one)
+			if blockEnd.Cmp(last) <= 0 {
+				break
+			}
+			size--
+		}
+
+		ip := make(net.IP, len(start))
+		cur.FillBytes(ip)
+		cidrs = append(cidrs, ip.String()+"/"+strconv.Itoa(bits-size))
+
+		cur.Add(cur, new(big.Int).Lsh(one, uint(size)))
+	}
+	return cidrs
+}
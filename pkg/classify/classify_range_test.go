@@ -0,0 +1,90 @@
+package classify
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseIPRange(t *testing.T) {
+	tests := []struct {
+		target string
+		start  string
+		end    string
+		format string
+		ok     bool
+	}{
+		{"10.0.0.0/24", "10.0.0.0", "10.0.0.255", "10.0.0.0/24", true},
+		{"10.0.0.7/30", "10.0.0.4", "10.0.0.7", "10.0.0.4/30", true},
+		{"1.2.3.4-1.2.3.50", "1.2.3.4", "1.2.3.50", "1.2.3.4-1.2.3.50", true},
+		{" 1.2.3.4 - 50 ", "1.2.3.4", "1.2.3.50", "1.2.3.4-1.2.3.50", true},
+		{"2001:db8::/126", "2001:db8::", "2001:db8::3", "2001:db8::/126", true},
+		{"2001:db8::1-2001:db8::9", "2001:db8::1", "2001:db8::9", "2001:db8::1-2001:db8::9", true},
+		{"1.2.3.50-1.2.3.4", "", "", "", false},
+		{"1.2.3.4-256", "", "", "", false},
+		{"1.2.3.4-2001:db8::1", "", "", "", false},
+		{"2001:db8::1-9", "", "", "", false},
+		{"10.0.0.0/33", "", "", "", false},
+		{"1.2.3.4", "", "", "", false},
+		{"example.com", "", "", "", false},
+	}
+
+	for _, tt := range tests {
+		start, end, format, ok := ParseIPRange(tt.target)
+		if ok != tt.ok {
+			t.Errorf("%q: ok = %v, want %v", tt.target, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if start.String() != tt.start || end.String() != tt.end || format != tt.format {
+			t.Errorf("%q: got (%s, %s, %s), want (%s, %s, %s)", tt.target, start, end, format, tt.start, tt.end, tt.format)
+		}
+	}
+}
+
+func TestExpandIPRange(t *testing.T) {
+	start, end, _, _ := ParseIPRange("10.0.0.254-10.0.1.1")
+	ips, err := ExpandIPRange(start, end, 4)
+	if err != nil {
+		t.Fatalf("ExpandIPRange: %v", err)
+	}
+	want := []string{"10.0.0.254", "10.0.0.255", "10.0.1.0", "10.0.1.1"}
+	if !reflect.DeepEqual(ips, want) {
+		t.Errorf("got %v, want %v", ips, want)
+	}
+
+	// 超过 --range-max 时不展开, 由调用方作为整体分析
+	if _, err := ExpandIPRange(start, end, 3); err == nil {
+		t.Error("expected error when range size exceeds max")
+	}
+
+	start, end, _, _ = ParseIPRange("2001:db8::/64")
+	if _, err := ExpandIPRange(start, end, 65536); err == nil {
+		t.Error("expected error for a /64 range")
+	}
+}
+
+func TestCollapseIPs(t *testing.T) {
+	tests := []struct {
+		ips  []string
+		want []string
+	}{
+		{[]string{"1.1.1.1", "1.1.1.2", "1.1.1.3"}, []string{"1.1.1.1/32", "1.1.1.2/31"}},
+		{[]string{"10.0.0.3", "10.0.0.0", "10.0.0.2", "10.0.0.1", "10.0.0.1", "bad"}, []string{"10.0.0.0/30"}},
+		{[]string{"2001:db8::1", "8.8.8.8", "2001:db8::"}, []string{"8.8.8.8/32", "2001:db8::/127"}},
+		{[]string{"255.255.255.255", "255.255.255.254"}, []string{"255.255.255.254/31"}},
+		{nil, nil},
+	}
+	for _, tt := range tests {
+		if got := CollapseIPs(tt.ips); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("CollapseIPs(%v) = %v, want %v", tt.ips, got, tt.want)
+		}
+	}
+
+	got := RangeToCIDRs(net.ParseIP("0.0.0.0").To4(), net.ParseIP("255.255.255.255").To4())
+	if !reflect.DeepEqual(got, []string{"0.0.0.0/0"}) {
+		t.Errorf("RangeToCIDRs full range = %v", got)
+	}
+}
@@ -0,0 +1,31 @@
+package classify
+
+import "testing"
+
+func TestClassifyTargetLocation(t *testing.T) {
+	tests := []struct {
+		input  string
+		scheme string
+		port   string
+		path   string
+		url    string
+	}{
+		{"https://a.com/login?x=1", "https", "443", "/login", "https://a.com:443"},
+		{"HTTP://a.com:8080", "http", "8080", "", "http://a.com:8080"},
+		{"a.com:8443", "", "8443", "", "https://a.com:8443"},
+		{"[2001:db8::1]:80", "", "80", "", "http://[2001:db8::1]:80"},
+		{"a.com", "", "", "", "http://a.com:80"},
+		{"1.1.1.1", "", "", "", "http://1.1.1.1:80"},
+	}
+
+	for _, tt := range tests {
+		_, entry := ClassifyTarget(tt.input)
+		if entry.Scheme != tt.scheme || entry.Port != tt.port || entry.Path != tt.path {
+			t.Errorf("%s: got (%s, %s, %s), want (%s, %s, %s)",
+				tt.input, entry.Scheme, entry.Port, entry.Path, tt.scheme, tt.port, tt.path)
+		}
+		if got := BuildURL(entry.Scheme, entry.FMT, entry.Port); got != tt.url {
+			t.Errorf("%s: url = %s, want %s", tt.input, got, tt.url)
+		}
+	}
+}
@@ -3,34 +3,15 @@ package classify
 import (
 	"net"
 	"net/url"
-	"regexp"
 	"strings"
 )
 
-// countURLs 统计列表中来自 URL 的条目数量
-func countURLs(entries []TargetEntry) int {
-	count := 0
-	for _, e := range entries {
-		if e.FromUrl {
-			count++
-		}
-	}
-	return count
-}
-
 // isIpv4 判断输入字符串是否是 IPv4
 func isIpv4(ipStr string) bool {
 	ip := net.ParseIP(strings.TrimSpace(ipStr))
 	return ip != nil && ip.To4() != nil
 }
 
-// isValidDomain 使用正则判断是否是合法域名
-func isValidDomain(domain string) bool {
-	domainRegex := `^([a-zA-Z0-9-]+\.)*[a-zA-Z0-9-]+\.[a-zA-Z]{2,}$`
-	matched, _ := regexp.MatchString(domainRegex, domain)
-	return matched
-}
-
 // IsValidIP 检查字符串是否是一个纯 IP 地址（不包含端口、路径等）
 func IsValidIP(target string) (bool, string) {
 	ip := net.ParseIP(strings.TrimSpace(target))
@@ -44,7 +25,7 @@ func IsValidIP(target string) (bool, string) {
 func classifyTarget(target string) (string, string, string, bool, bool) {
 	target = strings.TrimSpace(target)
 	if target == "" {
-		return "InvalidEntries", "", "", false, false
+		return CategoryInvalid, "", "", false, false
 	}
 
 	// 先尝试作为 URL 解析
@@ -54,18 +35,18 @@ func classifyTarget(target string) (string, string, string, bool, bool) {
 		if err == nil {
 			ipStr := strings.Trim(host, "[]") // 去掉 IPv6 方括号
 			if isIP, pureIP := IsValidIP(ipStr); isIP {
-				return "IP", target, pureIP, true, isIpv4(pureIP)
+				return CategoryIP, target, pureIP, true, isIpv4(pureIP)
 			}
-			if isValidDomain(host) {
-				return "Domain", target, host, true, false
+			if domain, ok := normalizeDomain(host); ok {
+				return CategoryDomain, target, domain, true, false
 			}
 		} else {
 			ipStr := strings.Trim(u.Host, "[]")
 			if isIP, pureIP := IsValidIP(ipStr); isIP {
-				return "IP", target, pureIP, true, isIpv4(pureIP)
+				return CategoryIP, target, pureIP, true, isIpv4(pureIP)
 			}
-			if isValidDomain(u.Host) {
-				return "Domain", target, u.Host, true, false
+			if domain, ok := normalizeDomain(u.Host); ok {
+				return CategoryDomain, target, domain, true, false
 			}
 		}
 	}
@@ -75,24 +56,86 @@ func classifyTarget(target string) (string, string, string, bool, bool) {
 	if err == nil {
 		ipStr := strings.Trim(host, "[]") // 去掉 IPv6 方括号
 		if isIP, pureIP := IsValidIP(ipStr); isIP {
-			return "IP", target, pureIP, false, isIpv4(pureIP)
+			return CategoryIP, target, pureIP, false, isIpv4(pureIP)
 		}
-		if isValidDomain(host) {
-			return "Domain", target, host, false, false
+		if domain, ok := normalizeDomain(host); ok {
+			return CategoryDomain, target, domain, false, false
 		}
 	}
 
+	// 判断 CIDR 或 IP 区间
+	if start, _, rangeFmt, ok := ParseIPRange(target); ok {
+		return CategoryRange, target, rangeFmt, false, start.To4() != nil
+	}
+
 	// 判断纯 IP 地址
 	if isIP, pureIP := IsValidIP(target); isIP {
-		return "IP", target, pureIP, false, isIpv4(pureIP)
+		return CategoryIP, target, pureIP, false, isIpv4(pureIP)
 	}
 
 	// 判断纯域名
-	if isValidDomain(target) {
-		return "Domain", target, target, false, false
+	if domain, ok := normalizeDomain(target); ok {
+		return CategoryDomain, target, domain, false, false
+	}
+
+	return CategoryInvalid, target, "", false, false
+}
+
+// defaultPorts 常见协议的默认端口
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ws":    "80",
+	"wss":   "443",
+	"ftp":   "21",
+}
+
+// parseLocation 从 URL 或 host:port 形式的输入中提取协议、端口和路径
+func parseLocation(target string) (string, string, string) {
+	target = strings.TrimSpace(target)
+	if u, err := url.ParseRequestURI(target); err == nil && u.Scheme != "" && u.Host != "" {
+		scheme := strings.ToLower(u.Scheme)
+		port := u.Port()
+		if port == "" {
+			port = defaultPorts[scheme]
+		}
+		return scheme, port, u.Path
+	}
+	if _, port, err := net.SplitHostPort(target); err == nil {
+		return "", port, ""
+	}
+	return "", "", ""
+}
+
+// BuildURL 将目标重建为 scheme://host:port 形式, 未知协议时按端口推断, 均未知时使用 http
+func BuildURL(scheme, host, port string) string {
+	if scheme == "" {
+		scheme = "http"
+		if port == "443" || port == "8443" {
+			scheme = "https"
+		}
 	}
+	if port == "" {
+		port = defaultPorts[scheme]
+	}
+	if port == "" {
+		return scheme + "://" + host
+	}
+	return scheme + "://" + net.JoinHostPort(host, port)
+}
 
-	return "InvalidEntries", target, "", false, false
+// invalidReason 返回无效目标的原因说明
+func invalidReason(target string) string {
+	if target == "" {
+		return "empty target"
+	}
+	if u, err := url.ParseRequestURI(target); err == nil && u.Scheme != "" && u.Host != "" {
+		return "invalid host in url: " + u.Host
+	}
+	if host, _, err := net.SplitHostPort(target); err == nil {
+		return "invalid host: " + host
+	}
+	return "not a valid ip or domain"
 }
 
 // ExtractFMTsPtr 从 TargetEntry 切片中提取所有 FMT 字段，返回字符串切片
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/winezer0/cdninfo/pkg/maputils"
+	"github.com/winezer0/cdninfo/pkg/progress"
 
 	"github.com/miekg/dns"
 )
@@ -20,6 +21,9 @@ type DNSResult struct {
 	NS    []string          `json:"NS,omitempty"`
 	TXT   []string          `json:"TXT,omitempty"`
 	Error map[string]string `json:"Error,omitempty"` // key: record type, value: error message
+
+	Status string        `json:"Status,omitempty"` // 解析状态: ok/no_records/nxdomain/servfail/timeout/error
+	Errors *ErrorSummary `json:"Errors,omitempty"` // 合并多个解析器结果时保留的错误汇总
 }
 
 // NewEmptyDNSQueryResult 返回一个空的 DNS 查询结果对象
@@ -43,6 +47,7 @@ type ResolverDNSResultMap = map[string]*DNSResult
 type DomainDNSResultMap = map[string]*DNSResult
 
 // ResolveDNS 查询指定类型的DNS记录，支持超时
+// 响应码不是 NOERROR 时返回 RcodeError, 同时返回应答中已有的记录, 如 NXDOMAIN 响应中指向不存在目标的 CNAME
 func ResolveDNS(domain, dnsServer, queryType string, timeout time.Duration) ([]string, error) {
 	client := &dns.Client{Timeout: timeout}
 	m := &dns.Msg{}
@@ -52,6 +57,9 @@ func ResolveDNS(domain, dnsServer, queryType string, timeout time.Duration) ([]s
 	if err != nil {
 		return nil, err
 	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return parseRecord(resp), &RcodeError{Rcode: resp.Rcode}
+	}
 	return parseRecord(resp), nil
 }
 
@@ -95,12 +103,14 @@ func setRecord(result *DNSResult, qType string, recs []string) {
 }
 
 // ResolveDNSWithResolversMulti 支持多个 domain，并发控制，返回结构化结果（使用指针优化 map 操作）
+// stats 用于记录每个解析器的查询统计, 为 nil 时不记录
 func ResolveDNSWithResolversMulti(
 	domains []string,
 	recordTypes []string,
 	resolvers []string,
 	timeout time.Duration,
 	maxConcurrency int,
+	stats *progress.Stats,
 ) DomainResolverDNSResultMap {
 	if len(recordTypes) == 0 {
 		recordTypes = DefaultRecordTypesSlice
@@ -132,13 +142,13 @@ func ResolveDNSWithResolversMulti(
 					defer func() { <-sem }() // 释放令牌
 
 					recs, err := ResolveDNS(domain, resolver, qType, timeout)
+					stats.RecordDNSQuery(resolver, queryError(err))
 
 					mu.Lock()
 					if err != nil {
 						results[domain][resolver].Error[qType] = err.Error()
-					} else {
-						setRecord(results[domain][resolver], qType, recs)
 					}
+					setRecord(results[domain][resolver], qType, recs)
 					mu.Unlock()
 				}(domain, resolver, qType)
 			}
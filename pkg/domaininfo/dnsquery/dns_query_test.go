@@ -3,8 +3,12 @@ package dnsquery
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"reflect"
 	"testing"
 	"time"
+
+	"github.com/miekg/dns"
 )
 
 // TestQueryDNS 测试单个 DNS 记录查询功能
@@ -40,7 +44,7 @@ func TestResolveAllDNSWithResolvers(t *testing.T) {
 	}
 
 	start := time.Now()
-	domainResolverDNSResultMap := ResolveDNSWithResolversMulti(domains, nil, resolvers, 5*time.Second, 15)
+	domainResolverDNSResultMap := ResolveDNSWithResolversMulti(domains, nil, resolvers, 5*time.Second, 15, nil)
 	elapsed := time.Since(start)
 	t.Logf("✅ ResolveDNSWithResolversAtom 总共查询 %d 个解析器，耗时: %v", len(resolvers), elapsed)
 
@@ -53,3 +57,39 @@ func TestResolveAllDNSWithResolvers(t *testing.T) {
 		fmt.Println(string(b))
 	}
 }
+
+// TestResolveDNSDanglingCNAME NXDOMAIN 响应中的 CNAME 仍然保留, 用于识别指向已失效 CDN 资源的域名
+func TestResolveDNSDanglingCNAME(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("listen udp failed: %v", err)
+	}
+	server := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeNameError)
+		cname, _ := dns.NewRR(r.Question[0].Name + " 300 IN CNAME gone.cdn.example.net.")
+		m.Answer = append(m.Answer, cname)
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer server.Shutdown()
+
+	resolver := conn.LocalAddr().String()
+	records, err := ResolveDNS("shop.example.com", resolver, "A", 2*time.Second)
+	if err == nil || ErrorStatus(err.Error()) != StatusNXDomain {
+		t.Fatalf("expected NXDOMAIN error, got %v", err)
+	}
+	if !reflect.DeepEqual(records, []string{"gone.cdn.example.net"}) {
+		t.Fatalf("unexpected records: %v", records)
+	}
+
+	merged := MergeDomainResolverResultMap(ResolveDNSWithResolversMulti(
+		[]string{"shop.example.com"}, []string{"A", "CNAME"}, []string{resolver}, 2*time.Second, 2, nil))
+	result := merged["shop.example.com"]
+	if !reflect.DeepEqual(result.CNAME, []string{"gone.cdn.example.net"}) || len(result.A) != 0 {
+		t.Fatalf("unexpected merged result: %+v", result)
+	}
+	if result.Errors == nil || result.Errors.Rcodes["NXDOMAIN"] != 2 {
+		t.Fatalf("nxdomain not kept in error summary: %+v", result.Errors)
+	}
+}
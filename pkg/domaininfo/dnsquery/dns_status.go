@@ -0,0 +1,208 @@
+package dnsquery
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// 域名解析状态
+const (
+	StatusOK        = "ok"
+	StatusNoRecords = "no_records"
+	StatusNXDomain  = "nxdomain"
+	StatusServFail  = "servfail"
+	StatusTimeout   = "timeout"
+	StatusError     = "error"
+)
+
+// statusPriority 多个解析器状态不一致时的优先级，权威的 NXDOMAIN 最可信
+var statusPriority = map[string]int{
+	StatusError:    1,
+	StatusTimeout:  2,
+	StatusServFail: 3,
+	StatusNXDomain: 4,
+}
+
+// RcodeError DNS 响应码不是 NOERROR 时返回的错误
+type RcodeError struct {
+	Rcode int
+}
+
+func (e *RcodeError) Error() string {
+	return dns.RcodeToString[e.Rcode]
+}
+
+// queryError 返回查询统计使用的错误, 解析器返回 NXDOMAIN 等响应码时查询本身成功, 只在解析状态中体现
+func queryError(err error) error {
+	var rcodeErr *RcodeError
+	if errors.As(err, &rcodeErr) {
+		return nil
+	}
+	return err
+}
+
+// ErrorStatus 根据错误信息判断解析状态
+func ErrorStatus(errMsg string) string {
+	switch {
+	case errMsg == dns.RcodeToString[dns.RcodeNameError]:
+		return StatusNXDomain
+	case errMsg == dns.RcodeToString[dns.RcodeServerFailure]:
+		return StatusServFail
+	case strings.Contains(strings.ToLower(errMsg), "timeout"):
+		return StatusTimeout
+	default:
+		return StatusError
+	}
+}
+
+// worseStatus 返回两个错误状态中优先级更高的一个
+func worseStatus(a, b string) string {
+	if statusPriority[b] > statusPriority[a] {
+		return b
+	}
+	return a
+}
+
+// hasAddressRecords 是否存在可用于分析的 A/AAAA/CNAME 记录
+func hasAddressRecords(result *DNSResult) bool {
+	return len(result.A) > 0 || len(result.AAAA) > 0 || len(result.CNAME) > 0
+}
+
+// UpdateStatus 根据记录和错误信息更新解析状态，errMsgs 为参与合并的所有错误
+func UpdateStatus(result *DNSResult, errMsgs ...string) {
+	if hasAddressRecords(result) {
+		result.Status = StatusOK
+		return
+	}
+
+	status := ""
+	if result.Status != StatusOK && result.Status != StatusNoRecords {
+		status = result.Status
+	}
+	for _, errMsg := range errMsgs {
+		status = worseStatus(status, ErrorStatus(errMsg))
+	}
+	if status == "" {
+		status = StatusNoRecords
+	}
+	result.Status = status
+}
+
+// StatusMessage 返回非 ok 状态的说明
+func StatusMessage(status string) string {
+	switch status {
+	case StatusNoRecords:
+		return "no A/AAAA/CNAME records"
+	case StatusNXDomain:
+		return "domain does not exist (NXDOMAIN)"
+	case StatusServFail:
+		return "resolver failure (SERVFAIL)"
+	case StatusTimeout:
+		return "dns query timed out"
+	case StatusError:
+		return "dns query failed"
+	default:
+		return ""
+	}
+}
+
+// ErrorSummary 多个解析器查询错误的汇总信息
+type ErrorSummary struct {
+	FailedResolvers []string          `json:"failed_resolvers,omitempty"` // 出现查询错误的解析器
+	FailedTypes     []string          `json:"failed_types,omitempty"`     // 所有解析器都未能给出结果的记录类型
+	Rcodes          map[string]int    `json:"rcodes,omitempty"`           // 非 NOERROR 响应码计数
+	Timeouts        int               `json:"timeouts"`                   // 超时次数
+	Failures        int               `json:"failures"`                   // 其他错误次数
+	Errors          map[string]string `json:"errors,omitempty"`           // key: 记录类型@解析器, value: 错误信息
+}
+
+// IsEmpty 是否没有任何错误
+func (s *ErrorSummary) IsEmpty() bool {
+	return s == nil || (len(s.Errors) == 0 && len(s.Rcodes) == 0 && s.Timeouts == 0 && s.Failures == 0)
+}
+
+// HasFailedType 判断某类记录是否查询失败
+func (s *ErrorSummary) HasFailedType(qType string) bool {
+	if s == nil {
+		return false
+	}
+	for _, t := range s.FailedTypes {
+		if t == qType {
+			return true
+		}
+	}
+	return false
+}
+
+// add 统计一条错误信息
+func (s *ErrorSummary) add(key, errMsg string) {
+	if s.Errors == nil {
+		s.Errors = make(map[string]string)
+	}
+	s.Errors[key] = errMsg
+
+	if _, ok := dns.StringToRcode[errMsg]; ok {
+		if s.Rcodes == nil {
+			s.Rcodes = make(map[string]int)
+		}
+		s.Rcodes[errMsg]++
+	} else if ErrorStatus(errMsg) == StatusTimeout {
+		s.Timeouts++
+	} else {
+		s.Failures++
+	}
+}
+
+// isDefinitive 错误是否为明确的否定应答（域名不存在），此时不视为查询失败
+func isDefinitive(errMsg string) bool {
+	return ErrorStatus(errMsg) == StatusNXDomain
+}
+
+// summarizeResolverErrors 汇总每个解析器每种记录类型的错误
+func summarizeResolverErrors(resultMap ResolverDNSResultMap) *ErrorSummary {
+	summary := &ErrorSummary{}
+	resolvers := make([]string, 0, len(resultMap))
+	for resolver := range resultMap {
+		resolvers = append(resolvers, resolver)
+	}
+	sort.Strings(resolvers)
+
+	failedCount := make(map[string]int)
+	for _, resolver := range resolvers {
+		dnsResult := resultMap[resolver]
+		if dnsResult == nil || len(dnsResult.Error) == 0 {
+			continue
+		}
+		summary.FailedResolvers = append(summary.FailedResolvers, resolver)
+		for qType, errMsg := range dnsResult.Error {
+			summary.add(qType+"@"+resolver, errMsg)
+			if !isDefinitive(errMsg) {
+				failedCount[qType]++
+			}
+		}
+	}
+
+	for _, qType := range DefaultRecordTypesSlice {
+		if len(resultMap) > 0 && failedCount[qType] == len(resultMap) {
+			summary.FailedTypes = append(summary.FailedTypes, qType)
+		}
+	}
+	return summary
+}
+
+// AddErrors 将额外的错误信息（如EDNS查询错误）合并到结果的错误汇总中
+func AddErrors(result *DNSResult, keyPrefix string, errMsgs []string) {
+	if len(errMsgs) == 0 {
+		return
+	}
+	if result.Errors == nil {
+		result.Errors = &ErrorSummary{}
+	}
+	for i, errMsg := range errMsgs {
+		result.Errors.add(fmt.Sprintf("%s_%d", keyPrefix, i), errMsg)
+	}
+}
@@ -0,0 +1,95 @@
+package dnsquery
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestMergeResolverResultStatus(t *testing.T) {
+	cases := []struct {
+		name   string
+		input  ResolverDNSResultMap
+		status string
+	}{
+		{
+			name: "records win over errors",
+			input: ResolverDNSResultMap{
+				"8.8.8.8": {A: []string{"1.1.1.1"}, Error: map[string]string{}},
+				"1.1.1.1": {Error: map[string]string{"A": "read udp: i/o timeout"}},
+			},
+			status: StatusOK,
+		},
+		{
+			name: "nxdomain over timeout",
+			input: ResolverDNSResultMap{
+				"8.8.8.8": {Error: map[string]string{"A": "NXDOMAIN"}},
+				"1.1.1.1": {Error: map[string]string{"A": "read udp: i/o timeout"}},
+			},
+			status: StatusNXDomain,
+		},
+		{
+			name: "servfail",
+			input: ResolverDNSResultMap{
+				"8.8.8.8": {Error: map[string]string{"A": "SERVFAIL", "AAAA": "REFUSED"}},
+			},
+			status: StatusServFail,
+		},
+		{
+			name: "empty answers",
+			input: ResolverDNSResultMap{
+				"8.8.8.8": {MX: []string{"10 mx.a.com"}, Error: map[string]string{}},
+			},
+			status: StatusNoRecords,
+		},
+	}
+
+	for _, c := range cases {
+		merged := mergeResolverResultMap(c.input)
+		if merged.Status != c.status {
+			t.Fatalf("%s: unexpected status, got=%s want=%s", c.name, merged.Status, c.status)
+		}
+	}
+}
+
+func TestSummarizeResolverErrors(t *testing.T) {
+	merged := mergeResolverResultMap(ResolverDNSResultMap{
+		"8.8.8.8": {CNAME: []string{"a.cdn.com"}, Error: map[string]string{"A": "read udp: i/o timeout"}},
+		"1.1.1.1": {Error: map[string]string{"A": "SERVFAIL", "AAAA": "NXDOMAIN"}},
+	})
+
+	summary := merged.Errors
+	if summary == nil {
+		t.Fatalf("errors should be preserved")
+	}
+	if len(summary.FailedResolvers) != 2 || summary.Timeouts != 1 || summary.Rcodes["SERVFAIL"] != 1 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	if !summary.HasFailedType("A") || summary.HasFailedType("AAAA") {
+		t.Fatalf("unexpected failed types: %v", summary.FailedTypes)
+	}
+	if summary.Errors["A@8.8.8.8"] == "" {
+		t.Fatalf("per record type error missing: %v", summary.Errors)
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	timeout := errors.New("read udp: i/o timeout")
+	cases := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"success", nil, nil},
+		{"nxdomain answered", &RcodeError{Rcode: dns.RcodeNameError}, nil},
+		{"wrapped servfail answered", fmt.Errorf("query: %w", &RcodeError{Rcode: dns.RcodeServerFailure}), nil},
+		{"timeout", timeout, timeout},
+	}
+	for _, c := range cases {
+		if got := queryError(c.err); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
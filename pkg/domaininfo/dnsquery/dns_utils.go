@@ -33,7 +33,11 @@ func NSServersAddPort(nsServers []string) []string {
 // mergeResolverResultMap  合并去重多个 ResolverResult
 func mergeResolverResultMap(resultMap ResolverDNSResultMap) DNSResult {
 	merged := DNSResult{}
+	var errMsgs []string
 	for _, dnsResult := range resultMap {
+		for _, errMsg := range dnsResult.Error {
+			errMsgs = append(errMsgs, errMsg)
+		}
 		merged.A = maputils.UniqueMergeSlices(merged.A, dnsResult.A)
 		merged.AAAA = maputils.UniqueMergeSlices(merged.AAAA, dnsResult.AAAA)
 		merged.CNAME = maputils.UniqueMergeSlices(merged.CNAME, dnsResult.CNAME)
@@ -41,6 +45,10 @@ func mergeResolverResultMap(resultMap ResolverDNSResultMap) DNSResult {
 		merged.MX = maputils.UniqueMergeSlices(merged.MX, dnsResult.MX)
 		merged.TXT = maputils.UniqueMergeSlices(merged.TXT, dnsResult.TXT)
 	}
+	UpdateStatus(&merged, errMsgs...)
+	if summary := summarizeResolverErrors(resultMap); !summary.IsEmpty() {
+		merged.Errors = summary
+	}
 	return merged
 }
 
@@ -50,6 +58,7 @@ func MergeDomainResolverResultMap(resultMap DomainResolverDNSResultMap) DomainDN
 	for domain, resolverMap := range resultMap {
 		mergedResult := mergeResolverResultMap(resolverMap)
 		OptimizeDNSResult(&mergedResult)
+		UpdateStatus(&mergedResult)
 		merged[domain] = &mergedResult
 	}
 	return merged
@@ -186,8 +195,9 @@ func LookupCNAMEChains(domain, dnsServer string, timeout time.Duration) ([]strin
 		}
 		visited[current] = struct{}{}
 
-		cnames, err := ResolveDNS(current, dnsServer, "CNAME", timeout)
-		if err != nil || len(cnames) == 0 {
+		// NXDOMAIN 响应中仍可能带有 CNAME, 保留到链条中
+		cnames, _ := ResolveDNS(current, dnsServer, "CNAME", timeout)
+		if len(cnames) == 0 {
 			break
 		}
 		//cnameChains = append(cnameChains, cnames...)
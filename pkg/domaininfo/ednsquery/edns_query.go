@@ -2,6 +2,7 @@ package ednsquery
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"sync"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/miekg/dns"
 	"github.com/winezer0/cdninfo/pkg/maputils"
+	"github.com/winezer0/cdninfo/pkg/progress"
 )
 
 // EDNSResult 存放最后格式化的结果
@@ -112,6 +114,7 @@ func ResolveEDNS(domain string, EDNSAddr string, dnsServer string, qType uint16,
 }
 
 // ResolveEDNSWithCities 批量解析多个域名在多个 DNS 和 location 下的 EDNS 响应
+// stats 用于记录每个城市的查询统计, 为 nil 时不记录
 func ResolveEDNSWithCities(
 	domains []string,
 	cities []map[string]string,
@@ -119,6 +122,7 @@ func ResolveEDNSWithCities(
 	maxConcurrency int,
 	queryCNAMES bool,
 	useSysNSQueryCNAMES bool,
+	stats *progress.Stats,
 ) DomainCityEDNSResultMap {
 	// Step 1: 异步并发预查所有域名的 CNAME / NS
 	ctx := context.Background()
@@ -177,6 +181,7 @@ func ResolveEDNSWithCities(
 						nsResult := ResolveEDNS(pr.FinalDomain, cityIP, dnsServer, dns.TypeNS, timeout)
 						mxResult := ResolveEDNS(pr.FinalDomain, cityIP, dnsServer, dns.TypeMX, timeout)
 						txtResult := ResolveEDNS(pr.FinalDomain, cityIP, dnsServer, dns.TypeTXT, timeout)
+						recordEDNSQueries(stats, city, aResult, aaaaResult, cnameResult, nsResult, mxResult, txtResult)
 
 						// 合并所有结果
 						allErrors := []string{}
@@ -239,3 +244,14 @@ func ResolveEDNSWithCities(
 
 	return resultMap
 }
+
+// recordEDNSQueries 记录某个城市的EDNS查询统计
+func recordEDNSQueries(stats *progress.Stats, city string, results ...EDNSResult) {
+	for _, result := range results {
+		var err error
+		if len(result.Errors) > 0 {
+			err = errors.New(result.Errors[0])
+		}
+		stats.RecordEDNSQuery(city, err)
+	}
+}
@@ -38,7 +38,7 @@ func TestResolveEDNSDomainsWithCities(t *testing.T) {
 	// === 第一次调用：启用 EDNS ===
 	t.Log("Running with EDNS enabled...")
 	start := time.Now()
-	resultsWithEDNS := ResolveEDNSWithCities(domains, cities, 5*time.Second, maxConcurrency, true, false)
+	resultsWithEDNS := ResolveEDNSWithCities(domains, cities, 5*time.Second, maxConcurrency, true, false, nil)
 	durationWithEDNS := time.Since(start)
 	printEDNSResultMap(MergeDomainCityEDNSResultMap(resultsWithEDNS))
 	fmt.Printf("✅ Time taken EDNS with cnames: %v\n\n", durationWithEDNS)
@@ -47,7 +47,7 @@ func TestResolveEDNSDomainsWithCities(t *testing.T) {
 	// === 第二次调用：禁用 EDNS ===
 	t.Log("Running with EDNS disabled...")
 	start = time.Now()
-	ednsEesultsNoCNMAES := ResolveEDNSWithCities(domains, cities, 3*time.Second, maxConcurrency, false, false)
+	ednsEesultsNoCNMAES := ResolveEDNSWithCities(domains, cities, 3*time.Second, maxConcurrency, false, false, nil)
 	ednsDurationNoCNMAES := time.Since(start)
 	printEDNSResultMap(MergeDomainCityEDNSResultMap(ednsEesultsNoCNMAES))
 	fmt.Printf("✅ Time taken EDNS without cnames:  %v\n\n", ednsDurationNoCNMAES)
@@ -7,6 +7,7 @@ import (
 	"github.com/winezer0/cdninfo/pkg/classify"
 	"github.com/winezer0/cdninfo/pkg/domaininfo/dnsquery"
 	"github.com/winezer0/cdninfo/pkg/domaininfo/ednsquery"
+	"github.com/winezer0/cdninfo/pkg/progress"
 )
 
 // DNSQueryConfig 存储DNS查询配置
@@ -19,6 +20,8 @@ type DNSQueryConfig struct {
 	QueryEDNSCNAMES    bool
 	QueryEDNSUseSysNS  bool
 	QueryType          string // 新增：查询类型选项 dns, edns, both
+
+	Stats *progress.Stats // 查询统计, 为空时不记录
 }
 
 // DNSProcessor DNS查询处理器
@@ -63,6 +66,7 @@ func (pro *DNSProcessor) Process() *dnsquery.DomainDNSResultMap {
 				pro.DNSQueryConfig.Resolvers,
 				pro.DNSQueryConfig.Timeout,
 				pro.DNSQueryConfig.MaxDNSConcurrency,
+				pro.DNSQueryConfig.Stats,
 			)
 		}()
 	}
@@ -79,6 +83,7 @@ func (pro *DNSProcessor) Process() *dnsquery.DomainDNSResultMap {
 				pro.DNSQueryConfig.MaxEDNSConcurrency,
 				pro.DNSQueryConfig.QueryEDNSCNAMES,
 				pro.DNSQueryConfig.QueryEDNSUseSysNS,
+				pro.DNSQueryConfig.Stats,
 			)
 		}()
 	}
@@ -121,6 +126,7 @@ func (pro *DNSProcessor) FastProcess() *dnsquery.DomainDNSResultMap {
 		pro.DNSQueryConfig.Resolvers,
 		pro.DNSQueryConfig.Timeout,
 		pro.DNSQueryConfig.MaxDNSConcurrency,
+		pro.DNSQueryConfig.Stats,
 	)
 
 	// 合并结果
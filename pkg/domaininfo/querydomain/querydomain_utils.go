@@ -33,9 +33,11 @@ func MergeEDNSMapToDNSMap(dnsMap dnsquery.DomainDNSResultMap, ednsMap ednsquery.
 			key := fmt.Sprintf("edns_error_%d", i)
 			dnsResult.Error[key] = err
 		}
+		dnsquery.AddErrors(dnsResult, "edns_error", ednsResult.Errors)
 
 		//优化整理
 		dnsquery.OptimizeDNSResult(dnsResult)
+		dnsquery.UpdateStatus(dnsResult, ednsResult.Errors...)
 	}
 	return dnsMap
 }
@@ -1,7 +1,10 @@
 package fileutils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -20,7 +23,7 @@ func IsEmptyFile(filename string) bool {
 // IsFileExists 判断是否是普通文件存在
 func IsFileExists(filename string) bool {
 	info, err := os.Stat(filename)
-	if os.IsNotExist(err) {
+	if err != nil {
 		return false
 	}
 	return !info.IsDir()
@@ -149,3 +152,18 @@ func MakeDirs(path string, isFile bool) error {
 	}
 	return nil
 }
+
+// HashFile 计算文件内容的 sha256 值，以流的方式读取不占用额外内存
+func HashFile(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
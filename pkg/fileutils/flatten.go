@@ -0,0 +1,312 @@
+package fileutils
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultListSep 列表值展开为单个单元格时的默认分隔符
+const DefaultListSep = ";"
+
+// FlatRow 展开后的单行数据
+type FlatRow struct {
+	Columns []string          // 按结构体字段定义顺序排列的列名
+	values  map[string]string // 小写列名到值的映射, 包含 map 字段的 name.key 形式
+	maps    map[string]bool   // map 类型的列, 其 name.key 形式的列由数据决定
+}
+
+// Get 按列名(不区分大小写)获取值, 也可以使用 json 字段的原始名称, 如 IsCdn 对应 is_cdn 列
+func (r *FlatRow) Get(column string) (string, bool) {
+	value, ok := r.values[strings.ToLower(column)]
+	if !ok {
+		value, ok = r.values[snakeColumn(column)]
+	}
+	return value, ok
+}
+
+// Has 判断列是否存在, map 列的 name.key 形式总是视为存在
+func (r *FlatRow) Has(column string) bool {
+	if _, ok := r.Get(column); ok {
+		return true
+	}
+	for _, name := range []string{strings.ToLower(column), snakeColumn(column)} {
+		if index := strings.LastIndexByte(name, '.'); index > 0 && r.maps[name[:index]] {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckColumns 检查 columns 是否都是 item 展开后的列, 列由类型决定, item 可以为零值
+func CheckColumns(item interface{}, columns []string) error {
+	row := Flatten(item, "")
+	var unknown []string
+	for _, column := range columns {
+		if !row.Has(column) {
+			unknown = append(unknown, column)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown columns: %s, available: %s", strings.Join(unknown, ","), strings.Join(row.Columns, ","))
+	}
+	return nil
+}
+
+// Flatten 将结构体展开为固定顺序的列, 嵌套结构体展开为 parent.field 列
+// 列名为 json 名称的小写下划线形式, 如 IsCdn 为 is_cdn, Ipv4Asn.asOrganisationName 为 ipv4_asn.as_organisation_name
+// 列表值使用 listSep 连接, 结构体列表按子字段分别连接, map 输出为排序后的 k=v 对
+// 列由类型决定而不是由数据决定, 空指针和空列表同样输出对应的列
+func Flatten(item interface{}, listSep string) *FlatRow {
+	if listSep == "" {
+		listSep = DefaultListSep
+	}
+	row := &FlatRow{values: make(map[string]string), maps: make(map[string]bool)}
+	value := reflect.ValueOf(item)
+	if !value.IsValid() {
+		row.add("value", "")
+		return row
+	}
+
+	t := value.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || isLeafType(t) {
+		// 非结构体数据按单列输出
+		row.add("value", formatLeaf(value, listSep))
+		return row
+	}
+	row.flattenStruct("", value, t, listSep)
+	return row
+}
+
+func (r *FlatRow) add(column, value string) {
+	r.Columns = append(r.Columns, column)
+	r.values[strings.ToLower(column)] = value
+}
+
+// flattenStruct 按字段顺序展开结构体, value 无效时输出空值
+func (r *FlatRow) flattenStruct(prefix string, value reflect.Value, t reflect.Type, listSep string) {
+	value = derefValue(value)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, ok := fieldName(field)
+		if !ok {
+			continue
+		}
+
+		var fieldValue reflect.Value
+		if value.IsValid() {
+			fieldValue = value.Field(i)
+		}
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		// 匿名嵌入的结构体字段提升到当前层级
+		if field.Anonymous && fieldType.Kind() == reflect.Struct && name == "" {
+			r.flattenStruct(prefix, fieldValue, fieldType, listSep)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		r.flattenField(joinColumn(prefix, snakeCase(name)), fieldValue, fieldType, listSep)
+	}
+}
+
+// flattenField 展开单个字段
+func (r *FlatRow) flattenField(column string, value reflect.Value, t reflect.Type, listSep string) {
+	switch {
+	case isLeafType(t):
+		r.add(column, formatLeaf(value, listSep))
+	case t.Kind() == reflect.Struct:
+		r.flattenStruct(column, value, t, listSep)
+	case t.Kind() == reflect.Map:
+		r.add(column, formatLeaf(value, listSep))
+		r.maps[strings.ToLower(column)] = true
+		// map 的每个键可以通过 column.key 单独选择
+		value = derefValue(value)
+		if value.IsValid() {
+			for _, key := range value.MapKeys() {
+				r.values[strings.ToLower(joinColumn(column, fmt.Sprint(key.Interface())))] = formatLeaf(value.MapIndex(key), listSep)
+			}
+		}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		elemType := t.Elem()
+		for elemType.Kind() == reflect.Ptr {
+			elemType = elemType.Elem()
+		}
+		if elemType.Kind() == reflect.Struct && !isLeafType(elemType) {
+			r.flattenStructList(column, value, elemType, listSep)
+			return
+		}
+		r.add(column, formatLeaf(value, listSep))
+	default:
+		r.add(column, formatLeaf(value, listSep))
+	}
+}
+
+// flattenStructList 结构体列表按子字段展开, 每列为各元素对应字段值的连接
+func (r *FlatRow) flattenStructList(column string, value reflect.Value, elemType reflect.Type, listSep string) {
+	template := &FlatRow{values: make(map[string]string), maps: make(map[string]bool)}
+	template.flattenStruct(column, reflect.Value{}, elemType, listSep)
+
+	value = derefValue(value)
+	parts := make([][]string, len(template.Columns))
+	if value.IsValid() {
+		for i := 0; i < value.Len(); i++ {
+			elem := &FlatRow{values: make(map[string]string), maps: make(map[string]bool)}
+			elem.flattenStruct(column, value.Index(i), elemType, listSep)
+			for j, name := range template.Columns {
+				v, _ := elem.Get(name)
+				parts[j] = append(parts[j], v)
+			}
+		}
+	}
+	for j, name := range template.Columns {
+		r.add(name, strings.Join(parts[j], listSep))
+	}
+}
+
+// formatLeaf 将值格式化为单元格内容
+func formatLeaf(value reflect.Value, listSep string) string {
+	value = derefValue(value)
+	if !value.IsValid() {
+		return ""
+	}
+
+	if value.CanInterface() {
+		switch v := value.Interface().(type) {
+		case time.Time:
+			if v.IsZero() {
+				return ""
+			}
+			return v.Format(time.RFC3339)
+		case []byte:
+			return string(v)
+		case encoding.TextMarshaler:
+			text, err := v.MarshalText()
+			if err == nil {
+				return string(text)
+			}
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return value.String()
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 64)
+	case reflect.Slice, reflect.Array:
+		parts := make([]string, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			parts = append(parts, formatLeaf(value.Index(i), listSep))
+		}
+		return strings.Join(parts, listSep)
+	case reflect.Map:
+		// map 的键值对使用逗号分隔, 与列表分隔符区分
+		pairs := make([]string, 0, value.Len())
+		for _, key := range value.MapKeys() {
+			pairs = append(pairs, fmt.Sprint(key.Interface())+"="+formatLeaf(value.MapIndex(key), listSep))
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ",")
+	case reflect.Struct:
+		row := Flatten(value.Interface(), listSep)
+		pairs := make([]string, 0, len(row.Columns))
+		for _, column := range row.Columns {
+			v, _ := row.Get(column)
+			pairs = append(pairs, column+"="+v)
+		}
+		return strings.Join(pairs, ",")
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
+
+// isLeafType 判断类型是否作为单个值输出
+func isLeafType(t reflect.Type) bool {
+	if t == reflect.TypeOf(time.Time{}) {
+		return true
+	}
+	if reflect.PointerTo(t).Implements(reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return false
+	default:
+		return true
+	}
+}
+
+// derefValue 解引用指针和接口, 空指针返回无效值
+func derefValue(value reflect.Value) reflect.Value {
+	for value.IsValid() && (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+// fieldName 返回字段的 json 名称, 标记为 "-" 的字段不输出
+func fieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return name, true
+}
+
+// snakeCase 将 json 名称转换为小写下划线形式, 如 IsCdn -> is_cdn, DNSError -> dns_error, Ipv4Asn -> ipv4_asn
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// snakeColumn 将 parent.field 形式的列名逐段转换为小写下划线形式
+func snakeColumn(column string) string {
+	parts := strings.Split(column, ".")
+	for i, part := range parts {
+		parts[i] = snakeCase(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func joinColumn(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
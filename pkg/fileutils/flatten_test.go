@@ -0,0 +1,120 @@
+package fileutils
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type flatAsn struct {
+	Number uint64 `json:"asn"`
+	Name   string `json:"name"`
+}
+
+type flatErrors struct {
+	Types  []string       `json:"types"`
+	Rcodes map[string]int `json:"rcodes"`
+}
+
+type flatInfo struct {
+	RAW    string              `json:"raw"`
+	IsCdn  bool                `json:"is_cdn"`
+	A      []string            `json:"A"`
+	Locate []map[string]string `json:"Locate"`
+	Asn    []flatAsn           `json:"Asn"`
+	Errors *flatErrors         `json:"Errors,omitempty"`
+	Extra  map[string]string   `json:"extra"`
+	hidden string
+}
+
+func TestFlatten(t *testing.T) {
+	info := flatInfo{
+		RAW:    "a.com",
+		IsCdn:  true,
+		A:      []string{"1.1.1.1", "2.2.2.2"},
+		Locate: []map[string]string{{"1.1.1.1": "US"}, {"2.2.2.2": "CN"}},
+		Asn:    []flatAsn{{13335, "CF"}, {4134, "CT"}},
+		Extra:  map[string]string{"owner": "bob", "env": "prod"},
+	}
+
+	row := Flatten(&info, "|")
+	wantColumns := []string{"raw", "is_cdn", "a", "locate", "asn.asn", "asn.name", "errors.types", "errors.rcodes", "extra"}
+	if !reflect.DeepEqual(row.Columns, wantColumns) {
+		t.Fatalf("columns = %v, want %v", row.Columns, wantColumns)
+	}
+
+	for column, want := range map[string]string{
+		"is_cdn":        "true",
+		"a":             "1.1.1.1|2.2.2.2",
+		"Locate":        "1.1.1.1=US|2.2.2.2=CN",
+		"Asn.asn":       "13335|4134",
+		"asn.name":      "CF|CT",
+		"Errors.rcodes": "",
+		"extra":         "env=prod,owner=bob",
+		"extra.owner":   "bob",
+	} {
+		if got, ok := row.Get(column); !ok || got != want {
+			t.Errorf("%s = %q (%v), want %q", column, got, ok, want)
+		}
+	}
+}
+
+func TestCsvStreamWriterFields(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "result.csv")
+	options := StreamOptions{Fields: []string{"raw", "A", "extra.owner"}, ListSep: "|"}
+	writer, err := OpenStreamWriterWithOptions("csv", outputFile, false, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.Write(flatInfo{RAW: "a.com", A: []string{"1.1.1.1", "2.2.2.2"}, Extra: map[string]string{"owner": "bob"}})
+	writer.Write(flatInfo{RAW: "b.com, c"})
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, _ := os.Open(outputFile)
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"raw", "A", "extra.owner"}, {"a.com", "1.1.1.1|2.2.2.2", "bob"}, {"b.com, c", "", ""}}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %v, want %v", records, want)
+	}
+
+	data, _ := os.ReadFile(outputFile)
+	if strings.Contains(string(data), `""`) {
+		t.Errorf("values should not be double quoted: %s", data)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"raw":                "raw",
+		"is_cdn":             "is_cdn",
+		"IsCdn":              "is_cdn",
+		"A":                  "a",
+		"AAAA":               "aaaa",
+		"DNSError":           "dns_error",
+		"Ipv4Asn":            "ipv4_asn",
+		"asOrganisationName": "as_organisation_name",
+	}
+	for name, want := range tests {
+		if got := snakeCase(name); got != want {
+			t.Errorf("snakeCase(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestCheckColumns(t *testing.T) {
+	if err := CheckColumns(flatInfo{}, []string{"raw", "IsCdn", "a", "asn.name", "extra.owner"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := CheckColumns(&flatInfo{}, []string{"raw", "cdn_company"}); err == nil || !strings.Contains(err.Error(), "cdn_company") {
+		t.Errorf("expected unknown column error, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package fileutils
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ExpandInputFiles 展开逗号分隔的文件路径和通配符, 返回去重后的文件列表
+// 存在的文件按字面路径使用, 因此文件名中可以包含逗号和 [ ] * ? 等字符
+// 通配符没有匹配到文件或文件不存在时返回错误
+func ExpandInputFiles(patterns string) ([]string, error) {
+	if path := strings.TrimSpace(patterns); IsFileExists(path) {
+		return []string{path}, nil
+	}
+
+	var files []string
+	seen := make(map[string]struct{})
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		matches := []string{pattern}
+		var err error
+		if !IsFileExists(pattern) {
+			matches, err = filepath.Glob(pattern)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid file pattern %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no file matched: %s", pattern)
+		}
+		for _, match := range matches {
+			if IsDirExists(match) {
+				continue
+			}
+			if _, ok := seen[match]; !ok {
+				seen[match] = struct{}{}
+				files = append(files, match)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no input file found in: %s", patterns)
+	}
+	return files, nil
+}
+
+// NewDecompressReader 根据数据头部识别 gzip/zstd 压缩并返回解压后的 reader, 未压缩的数据原样返回
+// 关闭返回的 reader 时同时关闭 source
+func NewDecompressReader(source io.ReadCloser) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(source)
+	header, _ := buffered.Peek(len(zstdMagic))
+
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		return &decompressReader{Reader: gz, closers: []func() error{gz.Close, source.Close}}, nil
+	case bytes.HasPrefix(header, zstdMagic):
+		zr, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		closeZstd := func() error {
+			zr.Close()
+			return nil
+		}
+		return &decompressReader{Reader: zr, closers: []func() error{closeZstd, source.Close}}, nil
+	default:
+		return &decompressReader{Reader: buffered, closers: []func() error{source.Close}}, nil
+	}
+}
+
+// decompressReader 依次关闭解压器和底层文件
+type decompressReader struct {
+	io.Reader
+	closers []func() error
+}
+
+func (r *decompressReader) Close() error {
+	var firstErr error
+	for _, closeFn := range r.closers {
+		if err := closeFn(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,75 @@
+package fileutils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestExpandInputFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.gz"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := ExpandInputFiles(filepath.Join(dir, "*.txt") + "," + filepath.Join(dir, "a.txt") + "," + filepath.Join(dir, "c.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Errorf("files = %v, want 3 unique files", files)
+	}
+
+	if _, err := ExpandInputFiles(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+
+	// 存在的文件按字面路径打开, 不作为通配符或逗号分隔的列表解析
+	for _, name := range []string{"hosts[1].txt", "a,b.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files, err := ExpandInputFiles(path)
+		if err != nil || len(files) != 1 || files[0] != path {
+			t.Errorf("%s: files = %v, err = %v", name, files, err)
+		}
+	}
+	files, err = ExpandInputFiles(filepath.Join(dir, "hosts[1].txt") + "," + filepath.Join(dir, "b.txt"))
+	if err != nil || len(files) != 2 {
+		t.Errorf("literal and plain files: files = %v, err = %v", files, err)
+	}
+}
+
+func TestNewDecompressReader(t *testing.T) {
+	content := "a.com\n# comment\nb.com\n"
+
+	var gzData bytes.Buffer
+	gz := gzip.NewWriter(&gzData)
+	gz.Write([]byte(content))
+	gz.Close()
+
+	var zstdData bytes.Buffer
+	zw, _ := zstd.NewWriter(&zstdData)
+	zw.Write([]byte(content))
+	zw.Close()
+
+	for name, data := range map[string][]byte{"plain": []byte(content), "gzip": gzData.Bytes(), "zstd": zstdData.Bytes()} {
+		reader, err := NewDecompressReader(io.NopCloser(bytes.NewReader(data)))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil || string(got) != content {
+			t.Errorf("%s: got %q, %v", name, got, err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package fileutils
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"os"
 	"sort"
@@ -101,6 +102,13 @@ func anyToString(value interface{}) string {
 		return v.Format(time.RFC3339)
 	case []byte:
 		return string(v)
+	case map[string]interface{}:
+		// 嵌套字段按 JSON 输出, 空值输出为空
+		if len(v) == 0 {
+			return ""
+		}
+		data, _ := json.Marshal(v)
+		return string(data)
 	default:
 		return fmt.Sprintf("%v", v)
 	}
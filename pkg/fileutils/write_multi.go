@@ -0,0 +1,131 @@
+package fileutils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/winezer0/xutils/logging"
+)
+
+// OutputSpec --out 指定的一个输出, 形如 type:path
+type OutputSpec struct {
+	Type string
+	Path string
+}
+
+// String 返回 type:path 形式
+func (s OutputSpec) String() string {
+	if s.Path == "" {
+		return s.Type
+	}
+	return s.Type + ":" + s.Path
+}
+
+// ParseOutputSpec 解析 type:path 形式的输出配置, 只按第一个冒号分割, 路径中可以包含冒号
+// sys 类型输出到 stdout 不需要路径, 其他类型省略路径时使用 result.<type>
+func ParseOutputSpec(value string) (OutputSpec, error) {
+	outputType, path, _ := strings.Cut(strings.TrimSpace(value), ":")
+	spec := OutputSpec{Type: strings.ToLower(strings.TrimSpace(outputType)), Path: strings.TrimSpace(path)}
+	if spec.Type == "" {
+		return spec, fmt.Errorf("invalid output spec %q, expected type:path", value)
+	}
+	if spec.Type == "sys" {
+		spec.Path = ""
+	} else if spec.Path == "" {
+		spec.Path = "result." + spec.Type
+	}
+	return spec, nil
+}
+
+// ConvertFunc 将写入的数据转换为某个输出器需要的格式, 返回 nil 表示该输出器跳过这条数据
+type ConvertFunc func(item interface{}) interface{}
+
+type multiSink struct {
+	name    string
+	writer  StreamWriter
+	convert ConvertFunc
+	err     error // 写入失败的错误, 不为 nil 时不再写入该输出器
+}
+
+// MultiStreamWriter 将每条数据写入多个输出器, 实现 StreamWriter 接口
+// 某个输出器写入失败时记录日志并停用该输出器, 其他输出器继续写入, 全部输出器都失败后才返回错误
+type MultiStreamWriter struct {
+	sinks []*multiSink
+
+	// FailFast 为 true 时任一输出器失败都返回错误, 断点续扫时使用, 避免将停用的输出器未写入的目标记录为完成
+	FailFast bool
+}
+
+// NewMultiStreamWriter 创建空的多路输出器, 通过 Add 添加输出器
+func NewMultiStreamWriter() *MultiStreamWriter {
+	return &MultiStreamWriter{}
+}
+
+// Add 添加一个输出器, name 用于日志中区分输出器, convert 为空时直接写入原始数据
+func (m *MultiStreamWriter) Add(name string, writer StreamWriter, convert ConvertFunc) {
+	m.sinks = append(m.sinks, &multiSink{name: name, writer: writer, convert: convert})
+}
+
+// Write 写入全部可用的输出器, 全部输出器都已失败或 FailFast 时任一输出器失败返回合并后的错误
+func (m *MultiStreamWriter) Write(item interface{}) error {
+	for _, sink := range m.sinks {
+		if sink.err != nil {
+			continue
+		}
+		value := item
+		if sink.convert != nil {
+			if value = sink.convert(item); value == nil {
+				continue
+			}
+		}
+		if err := sink.writer.Write(value); err != nil {
+			m.disable(sink, err)
+		}
+	}
+	return m.failed()
+}
+
+// disable 停用写入失败的输出器
+func (m *MultiStreamWriter) disable(sink *multiSink, err error) {
+	sink.err = fmt.Errorf("%s: %w", sink.name, err)
+	logging.Errorf("Write output [%s] failed, disabled: %v", sink.name, err)
+}
+
+// failed 全部输出器都已失败或 FailFast 时任一输出器失败返回合并后的错误, 否则返回 nil
+func (m *MultiStreamWriter) failed() error {
+	var errs []error
+	for _, sink := range m.sinks {
+		if sink.err == nil {
+			if !m.FailFast {
+				return nil
+			}
+			continue
+		}
+		errs = append(errs, sink.err)
+	}
+	return errors.Join(errs...)
+}
+
+// Sync 持久化全部可用的实现了 SyncWriter 的输出器中缓冲的结果, 失败的输出器同样被停用
+func (m *MultiStreamWriter) Sync() error {
+	for _, sink := range m.sinks {
+		if syncer, ok := sink.writer.(SyncWriter); ok && sink.err == nil {
+			if err := syncer.Sync(); err != nil {
+				m.disable(sink, err)
+			}
+		}
+	}
+	return m.failed()
+}
+
+// Close 关闭全部输出器
+func (m *MultiStreamWriter) Close() error {
+	var errs []error
+	for _, sink := range m.sinks {
+		if err := sink.writer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
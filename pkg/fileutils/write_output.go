@@ -4,25 +4,30 @@ import (
 	"fmt"
 	"github.com/winezer0/xutils/logging"
 	"os"
+	"reflect"
 	"strings"
 
 	"github.com/winezer0/cdninfo/pkg/maputils"
 )
 
+// WriteOutputToFile 按输出类型写入结果, webhook 和已注册的消息队列类型时 outputFile 为推送目标
 func WriteOutputToFile(data interface{}, outputType, outputFile string) error {
 	var err error
 
+	if IsRemoteOutput(outputType) {
+		if err = writeStreamItems(strings.ToLower(outputType), outputFile, data); err != nil {
+			return fmt.Errorf("推送 %s 失败: %w", outputType, err)
+		}
+		logging.Debugf("结果已推送到%s: %s\n", outputType, outputFile)
+		return nil
+	}
+
 	switch strings.ToLower(outputType) {
 	case "csv":
 		if outputFile == "" {
 			outputFile = "result.csv"
 		}
-		sliceToMaps, mapErr := maputils.ConvertStructsToMaps(data)
-		if mapErr == nil {
-			err = WriteCSV(outputFile, sliceToMaps, true)
-		} else {
-			err = WriteAny(outputFile, maputils.AnyToTxtStr(data))
-		}
+		err = writeStreamItems("csv", outputFile, data)
 
 	case "json":
 		if outputFile == "" {
@@ -30,6 +35,12 @@ func WriteOutputToFile(data interface{}, outputType, outputFile string) error {
 		}
 		err = os.WriteFile(outputFile, maputils.AnyToJsonBytes(data), 0644)
 
+	case "jsonl":
+		if outputFile == "" {
+			outputFile = "result.jsonl"
+		}
+		err = writeStreamItems("jsonl", outputFile, data)
+
 	case "txt":
 		if outputFile == "" {
 			outputFile = "result.txt"
@@ -48,3 +59,24 @@ func WriteOutputToFile(data interface{}, outputType, outputFile string) error {
 	logging.Debugf("结果已写入%s文件: %s\n", outputType, outputFile)
 	return nil
 }
+
+// writeStreamItems 使用流式输出器逐个写入切片中的元素, 非切片数据作为单个元素写入
+func writeStreamItems(outputType, outputFile string, data interface{}) error {
+	writer, err := NewStreamWriter(outputType, outputFile)
+	if err != nil {
+		return err
+	}
+
+	val := reflect.ValueOf(data)
+	if val.Kind() != reflect.Slice {
+		err = writer.Write(data)
+	} else {
+		for i := 0; i < val.Len() && err == nil; i++ {
+			err = writer.Write(val.Index(i).Interface())
+		}
+	}
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
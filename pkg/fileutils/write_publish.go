@@ -0,0 +1,92 @@
+package fileutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Publisher 消息队列的发布接口, 可以由 Kafka、NATS 等客户端实现
+// 每条结果作为一条消息发布, topic/subject 等目标信息在创建时确定
+type Publisher interface {
+	// Publish 发布一条消息, key 可用于 Kafka 分区, 不需要键的实现可以忽略
+	Publish(key string, value []byte) error
+	// Close 发送缓冲中的消息并关闭连接
+	Close() error
+}
+
+// PublisherOpener 根据输出目标创建 Publisher, target 为 --out name:target 中的 target, 如 broker 地址和 topic
+type PublisherOpener func(target string) (Publisher, error)
+
+var (
+	publishersMu sync.RWMutex
+	publishers   = make(map[string]PublisherOpener)
+)
+
+// RegisterPublisher 注册消息队列输出类型, 注册后可以作为输出类型使用, 如 kafka:broker:9092/topic
+// 名称不能与已有的文件输出类型相同, 重复注册时覆盖
+func RegisterPublisher(name string, opener PublisherOpener) {
+	publishersMu.Lock()
+	defer publishersMu.Unlock()
+	publishers[strings.ToLower(name)] = opener
+}
+
+// PublisherNames 返回已注册的消息队列输出类型
+func PublisherNames() []string {
+	publishersMu.RLock()
+	defer publishersMu.RUnlock()
+	names := make([]string, 0, len(publishers))
+	for name := range publishers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupPublisher(name string) (PublisherOpener, bool) {
+	publishersMu.RLock()
+	defer publishersMu.RUnlock()
+	opener, ok := publishers[strings.ToLower(name)]
+	return opener, ok
+}
+
+// IsRemoteOutput 判断输出类型是否推送到远程服务(webhook 或已注册的消息队列), 而不是写入文件
+func IsRemoteOutput(outputType string) bool {
+	if strings.ToLower(outputType) == "webhook" {
+		return true
+	}
+	_, ok := lookupPublisher(outputType)
+	return ok
+}
+
+// publisherStreamWriter 将每条结果编码为 JSON 后发布
+type publisherStreamWriter struct {
+	publisher Publisher
+	keyField  string
+}
+
+// NewPublisherStreamWriter 创建消息队列输出器, keyField 不为空时使用结果中该列的值作为消息键, 如 fmt
+func NewPublisherStreamWriter(publisher Publisher, keyField string) StreamWriter {
+	return &publisherStreamWriter{publisher: publisher, keyField: keyField}
+}
+
+func (w *publisherStreamWriter) Write(item interface{}) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	var key string
+	if w.keyField != "" {
+		key, _ = Flatten(item, "").Get(w.keyField)
+	}
+	if err := w.publisher.Publish(key, data); err != nil {
+		return fmt.Errorf("publish result failed: %w", err)
+	}
+	return nil
+}
+
+func (w *publisherStreamWriter) Close() error {
+	return w.publisher.Close()
+}
@@ -0,0 +1,409 @@
+package fileutils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/winezer0/cdninfo/pkg/maputils"
+	"github.com/winezer0/xutils/logging"
+)
+
+// StreamWriter 逐条写入结果的输出器，用于结果产生后立即输出
+type StreamWriter interface {
+	Write(item interface{}) error
+	Close() error
+}
+
+// SyncWriter 内部缓冲结果的输出器, Sync 将已写入的结果持久化或发送
+// 断点续扫需要在记录完成状态前调用, 避免中断时丢失已标记为完成的结果
+type SyncWriter interface {
+	Sync() error
+}
+
+// StreamOptions 流式输出的可选配置
+type StreamOptions struct {
+	Fields  []string // csv 输出的列及顺序, 不区分大小写, 为空时输出全部列
+	ListSep string   // csv 中列表值的分隔符, 默认 DefaultListSep
+
+	Template       *template.Template // txt/sys 输出时对每条结果执行的模板, 为空时使用 AnyToTxtStr
+	TemplateHeader string             // 使用模板时在输出开头写入一次的内容, 追加写入已有内容时跳过
+
+	Webhook WebhookOptions // webhook 输出的配置
+}
+
+// NewStreamWriter 根据输出类型创建流式输出器，sys 类型输出到 stdout
+func NewStreamWriter(outputType, outputFile string) (StreamWriter, error) {
+	return OpenStreamWriter(outputType, outputFile, false)
+}
+
+// OpenStreamWriter 创建流式输出器，appendMode 为 true 时在已有结果文件后继续写入
+func OpenStreamWriter(outputType, outputFile string, appendMode bool) (StreamWriter, error) {
+	return OpenStreamWriterWithOptions(outputType, outputFile, appendMode, StreamOptions{})
+}
+
+// OpenStreamWriterWithOptions 使用指定配置创建流式输出器
+func OpenStreamWriterWithOptions(outputType, outputFile string, appendMode bool, options StreamOptions) (StreamWriter, error) {
+	outputType = strings.ToLower(outputType)
+	if outputType == "sys" || outputType == "" {
+		return newTxtStreamWriter(os.Stdout, nil, options, true)
+	}
+
+	// 推送到远程服务时 outputFile 为 webhook 地址或消息队列的目标
+	if outputType == "webhook" {
+		return NewWebhookStreamWriter(outputFile, options.Webhook)
+	}
+	if opener, ok := lookupPublisher(outputType); ok {
+		publisher, err := opener(outputFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s publisher: %w", outputType, err)
+		}
+		return NewPublisherStreamWriter(publisher, "fmt"), nil
+	}
+
+	if outputFile == "" {
+		outputFile = "result." + outputType
+	}
+
+	switch outputType {
+	case "csv", "json", "jsonl", "txt":
+	default:
+		return nil, fmt.Errorf("unsupported stream output type: %s", outputType)
+	}
+
+	flag := os.O_CREATE | os.O_RDWR | os.O_TRUNC
+	if appendMode {
+		flag = os.O_CREATE | os.O_RDWR
+	}
+	file, err := os.OpenFile(outputFile, flag, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output file: %w", err)
+	}
+
+	var writer StreamWriter
+	switch outputType {
+	case "csv":
+		writer, err = newCsvStreamWriter(file, appendMode, options)
+	case "json":
+		writer, err = newJsonStreamWriter(file, appendMode)
+	case "jsonl":
+		writer, err = newJsonlStreamWriter(file, appendMode)
+	default:
+		var offset int64
+		if appendMode {
+			offset, err = truncateLastLine(file)
+		}
+		if err == nil {
+			writer, err = newTxtStreamWriter(file, file, options, offset == 0)
+		}
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return writer, nil
+}
+
+// txtStreamWriter 每条结果按 AnyToTxtStr 的格式或指定的模板输出
+type txtStreamWriter struct {
+	writer   *bufio.Writer
+	closer   io.Closer
+	template *template.Template
+	buf      bytes.Buffer
+}
+
+func newTxtStreamWriter(w io.Writer, closer io.Closer, options StreamOptions, writeHeader bool) (*txtStreamWriter, error) {
+	writer := &txtStreamWriter{writer: bufio.NewWriter(w), closer: closer, template: options.Template}
+	if writer.template != nil && writeHeader && options.TemplateHeader != "" {
+		if _, err := writer.writer.WriteString(options.TemplateHeader); err != nil {
+			return nil, err
+		}
+		if err := writer.writer.Flush(); err != nil {
+			return nil, err
+		}
+	}
+	return writer, nil
+}
+
+func (w *txtStreamWriter) Write(item interface{}) error {
+	var line string
+	if w.template != nil {
+		// 模板输出为空时跳过该条结果, 可在模板中按条件过滤
+		w.buf.Reset()
+		if err := w.template.Execute(&w.buf, item); err != nil {
+			return fmt.Errorf("execute output template failed: %w", err)
+		}
+		if w.buf.Len() == 0 {
+			return nil
+		}
+		line = strings.TrimSuffix(w.buf.String(), "\n")
+	} else if s, ok := item.(string); ok {
+		line = s
+	} else {
+		line = strings.TrimRight(maputils.AnyToTxtStr(item), "\n")
+	}
+	if _, err := w.writer.WriteString(line + "\n"); err != nil {
+		return err
+	}
+	// 每条结果都刷新，保证管道下游可以立即读取
+	return w.writer.Flush()
+}
+
+func (w *txtStreamWriter) Close() error {
+	if err := w.writer.Flush(); err != nil {
+		return err
+	}
+	if w.closer != nil {
+		return w.closer.Close()
+	}
+	return nil
+}
+
+// jsonStreamWriter 逐条写入 JSON 数组元素，关闭时补全数组结尾
+type jsonStreamWriter struct {
+	file  *os.File
+	count int
+}
+
+func newJsonStreamWriter(file *os.File, appendMode bool) (*jsonStreamWriter, error) {
+	w := &jsonStreamWriter{file: file}
+	if !appendMode {
+		return w, nil
+	}
+
+	// 截断到最后一个完整元素之后, 去掉已有数组的结尾 "]"，后续元素接在其后
+	size, count, err := jsonArrayEnd(file)
+	if err != nil {
+		return nil, err
+	}
+	w.count = count
+	if err := file.Truncate(size); err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(size, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// jsonArrayEnd 返回已有 JSON 数组中最后一个完整元素之后的位置和元素数量, 没有元素时位置为 0
+// 中断时数组可能缺少结尾 "]" 或最后一个元素不完整, 只保留完整的元素
+func jsonArrayEnd(file *os.File) (int64, int, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	token, err := decoder.Token()
+	if err == io.EOF {
+		return 0, 0, nil
+	}
+	if delim, ok := token.(json.Delim); err != nil || !ok || delim != '[' {
+		return 0, 0, fmt.Errorf("existing json output is not an array: %s", file.Name())
+	}
+
+	var end int64
+	count := 0
+	for decoder.More() {
+		var element json.RawMessage
+		if err := decoder.Decode(&element); err != nil {
+			break
+		}
+		end = decoder.InputOffset()
+		count++
+	}
+	if token, err := decoder.Token(); err != nil || token != json.Delim(']') {
+		logging.Warnf("Existing json output [%s] is incomplete, continue after the last complete result", file.Name())
+	}
+	return end, count, nil
+}
+
+func (w *jsonStreamWriter) Write(item interface{}) error {
+	data, err := json.MarshalIndent(item, "  ", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON 序列化失败: %w", err)
+	}
+
+	prefix := ",\n  "
+	if w.count == 0 {
+		prefix = "[\n  "
+	}
+	if _, err := w.file.WriteString(prefix + string(data)); err != nil {
+		return err
+	}
+	w.count++
+	return nil
+}
+
+func (w *jsonStreamWriter) Close() error {
+	tail := "\n]\n"
+	if offset, _ := w.file.Seek(0, io.SeekCurrent); offset == 0 {
+		tail = "[]\n"
+	}
+	if _, err := w.file.WriteString(tail); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// jsonlStreamWriter 每条结果写为一行 JSON
+type jsonlStreamWriter struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+func newJsonlStreamWriter(file *os.File, appendMode bool) (*jsonlStreamWriter, error) {
+	if appendMode {
+		if _, err := truncateLastLine(file); err != nil {
+			return nil, err
+		}
+	}
+	return &jsonlStreamWriter{file: file, writer: bufio.NewWriter(file)}, nil
+}
+
+// truncateLastLine 中断时最后一行可能不完整，截断到最后一个换行符之后并定位到文件末尾, 返回截断后的大小
+func truncateLastLine(file *os.File) (int64, error) {
+	size, err := lastLineEnd(file)
+	if err != nil {
+		return 0, err
+	}
+	if err := file.Truncate(size); err != nil {
+		return 0, err
+	}
+	if _, err := file.Seek(size, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// lastLineEnd 返回文件中最后一个换行符之后的位置，没有换行符时返回 0
+func lastLineEnd(file *os.File) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	buf := make([]byte, 64*1024)
+	end := info.Size()
+	for end > 0 {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+		chunk := buf[:end-start]
+		if _, err := file.ReadAt(chunk, start); err != nil && err != io.EOF {
+			return 0, err
+		}
+		if index := bytes.LastIndexByte(chunk, '\n'); index >= 0 {
+			return start + int64(index) + 1, nil
+		}
+		end = start
+	}
+	return 0, nil
+}
+
+func (w *jsonlStreamWriter) Write(item interface{}) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("JSON 序列化失败: %w", err)
+	}
+	data = append(data, '\n')
+	if _, err := w.writer.Write(data); err != nil {
+		return err
+	}
+	// 每条结果都刷新，保证下游可以逐行读取完整的结果
+	return w.writer.Flush()
+}
+
+func (w *jsonlStreamWriter) Close() error {
+	if err := w.writer.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// csvStreamWriter 将每条结果展开为固定顺序的列逐行写入 CSV
+// 未指定列时以第一条结果展开后的列作为表头
+type csvStreamWriter struct {
+	file    *os.File
+	writer  *csv.Writer
+	headers []string
+	listSep string
+	written bool // 表头是否已写入
+}
+
+func newCsvStreamWriter(file *os.File, appendMode bool, options StreamOptions) (*csvStreamWriter, error) {
+	w := &csvStreamWriter{file: file, writer: csv.NewWriter(file), listSep: options.ListSep}
+	for _, field := range options.Fields {
+		if field = strings.TrimSpace(field); field != "" {
+			w.headers = append(w.headers, field)
+		}
+	}
+	if !appendMode {
+		return w, nil
+	}
+
+	// 丢弃不完整的最后一行后沿用已有文件的表头
+	size, err := truncateLastLine(file)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	headers, err := csv.NewReader(file).Read()
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read existing csv header: %w", err)
+	}
+	if len(headers) > 0 {
+		w.headers = headers
+		w.written = true
+	}
+	if _, err := file.Seek(size, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *csvStreamWriter) Write(item interface{}) error {
+	row := Flatten(item, w.listSep)
+	if w.headers == nil {
+		w.headers = row.Columns
+	}
+	if !w.written {
+		w.written = true
+		for _, header := range w.headers {
+			if !row.Has(header) {
+				logging.Warnf("csv column [%s] not found in the first result", header)
+			}
+		}
+		if err := w.writer.Write(w.headers); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, len(w.headers))
+	for i, header := range w.headers {
+		record[i], _ = row.Get(header)
+	}
+	if err := w.writer.Write(record); err != nil {
+		return err
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *csvStreamWriter) Close() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
@@ -0,0 +1,328 @@
+package fileutils
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestJsonStreamWriter(t *testing.T) {
+	type row struct {
+		FMT   string `json:"fmt"`
+		IsCdn bool   `json:"is_cdn"`
+	}
+
+	for _, rows := range [][]row{nil, {{FMT: "a.com", IsCdn: true}, {FMT: "b.com"}}} {
+		outputFile := filepath.Join(t.TempDir(), "result.json")
+		writer, err := NewStreamWriter("json", outputFile)
+		if err != nil {
+			t.Fatalf("create writer failed: %v", err)
+		}
+		for _, r := range rows {
+			if err := writer.Write(r); err != nil {
+				t.Fatalf("write failed: %v", err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("close failed: %v", err)
+		}
+
+		data, _ := os.ReadFile(outputFile)
+		var got []row
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("invalid json output: %v\n%s", err, data)
+		}
+		if len(got) != len(rows) {
+			t.Fatalf("unexpected rows, got=%v want=%v", got, rows)
+		}
+	}
+}
+
+func TestJsonStreamWriterAppend(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "result.json")
+	for i, items := range [][]string{{"a.com"}, nil, {"b.com", "c.com"}} {
+		writer, err := OpenStreamWriter("json", outputFile, i > 0)
+		if err != nil {
+			t.Fatalf("open writer failed: %v", err)
+		}
+		for _, item := range items {
+			if err := writer.Write(item); err != nil {
+				t.Fatalf("write failed: %v", err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("close failed: %v", err)
+		}
+	}
+
+	data, _ := os.ReadFile(outputFile)
+	var got []string
+	if err := json.Unmarshal(data, &got); err != nil || len(got) != 3 {
+		t.Fatalf("unexpected appended output: %v\n%s", err, data)
+	}
+}
+
+func TestJsonStreamWriterAppendTruncated(t *testing.T) {
+	// 模拟中断时没有写入数组结尾, 且最后一个元素只写了一半
+	tests := map[string][]string{
+		"[\n  {\"raw\": \"a.com\"},\n  {\"raw\": \"b.": {"a.com", "c.com"},
+		"[\n  {\"raw\": \"a.com\"}":                    {"a.com", "c.com"},
+		"[\n  {\"ra":                                   {"c.com"},
+		"[":                                            {"c.com"},
+	}
+	for content, want := range tests {
+		file := filepath.Join(t.TempDir(), "result.json")
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		writer, err := OpenStreamWriter("json", file, true)
+		if err != nil {
+			t.Fatalf("%q: open writer failed: %v", content, err)
+		}
+		if err := writer.Write(map[string]string{"raw": "c.com"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		data, _ := os.ReadFile(file)
+		var got []struct {
+			RAW string `json:"raw"`
+		}
+		if err := json.Unmarshal(data, &got); err != nil || len(got) != len(want) {
+			t.Fatalf("%q: unexpected output: %v\n%s", content, err, data)
+		}
+		for i := range want {
+			if got[i].RAW != want[i] {
+				t.Errorf("%q: got %v, want %v", content, got, want)
+			}
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "result.json")
+	_ = os.WriteFile(file, []byte("{\"raw\": \"a.com\"}"), 0644)
+	if _, err := OpenStreamWriter("json", file, true); err == nil {
+		t.Error("expected error for json output that is not an array")
+	}
+}
+
+func TestJsonlStreamWriterAppend(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "result.jsonl")
+	// 模拟中断时写入了一半的最后一行
+	if err := os.WriteFile(file, []byte("{\"raw\":\"a.com\"}\n{\"raw\":\"b."), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	type row struct {
+		RAW string `json:"raw"`
+		FMT string `json:"fmt"`
+	}
+	writer, err := OpenStreamWriter("jsonl", file, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Write(row{RAW: "b.com", FMT: "b.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(file)
+	want := "{\"raw\":\"a.com\"}\n{\"raw\":\"b.com\",\"fmt\":\"b.com\"}\n"
+	if string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
+
+func TestStreamWriterAppendTruncated(t *testing.T) {
+	type row struct {
+		RAW string `json:"raw"`
+		FMT string `json:"fmt"`
+	}
+	tests := []struct {
+		outputType string
+		existing   string // 模拟中断时写入了一半的最后一行
+		want       string
+	}{
+		{"csv", "raw,fmt\na.com,a.com\nb.c", "raw,fmt\na.com,a.com\nb.com,b.com\n"},
+		{"csv", "raw,f", "raw,fmt\nb.com,b.com\n"},
+		{"txt", "a.com\nb.", "a.com\nb.com\n"},
+	}
+	for _, tt := range tests {
+		file := filepath.Join(t.TempDir(), "result."+tt.outputType)
+		if err := os.WriteFile(file, []byte(tt.existing), 0644); err != nil {
+			t.Fatal(err)
+		}
+		writer, err := OpenStreamWriter(tt.outputType, file, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var item interface{} = row{RAW: "b.com", FMT: "b.com"}
+		if tt.outputType == "txt" {
+			item = "b.com"
+		}
+		if err := writer.Write(item); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(file)
+		if string(data) != tt.want {
+			t.Errorf("%s %q: got %q, want %q", tt.outputType, tt.existing, data, tt.want)
+		}
+	}
+}
+
+func TestTxtStreamWriterTemplate(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "result.txt")
+	options := StreamOptions{
+		Template:       template.Must(template.New("t").Parse(`{{if .}}{{.}}{{end}}`)),
+		TemplateHeader: "# header\n",
+	}
+
+	for _, appendMode := range []bool{false, true} {
+		writer, err := OpenStreamWriterWithOptions("txt", filePath, appendMode, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range []string{"a.com", "", "b.com"} {
+			if err := writer.Write(item); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# header\na.com\nb.com\na.com\nb.com\n"; string(data) != want {
+		t.Fatalf("unexpected output: %q", data)
+	}
+}
+
+func TestMultiStreamWriter(t *testing.T) {
+	dir := t.TempDir()
+	jsonlFile, txtFile := filepath.Join(dir, "result.jsonl"), filepath.Join(dir, "result.txt")
+	jsonlWriter, err := NewStreamWriter("jsonl", jsonlFile)
+	if err != nil {
+		t.Fatalf("create writer failed: %v", err)
+	}
+	txtWriter, err := NewStreamWriter("txt", txtFile)
+	if err != nil {
+		t.Fatalf("create writer failed: %v", err)
+	}
+
+	multi := NewMultiStreamWriter()
+	multi.Add("jsonl", jsonlWriter, func(item interface{}) interface{} { return map[string]int{"n": item.(int)} })
+	// 只输出偶数
+	multi.Add("txt", txtWriter, func(item interface{}) interface{} {
+		if item.(int)%2 != 0 {
+			return nil
+		}
+		return strconv.Itoa(item.(int))
+	})
+	for i := 1; i <= 4; i++ {
+		if err := multi.Write(i); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if err := multi.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	jsonl, _ := os.ReadFile(jsonlFile)
+	txt, _ := os.ReadFile(txtFile)
+	if string(jsonl) != "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n{\"n\":4}\n" || string(txt) != "2\n4\n" {
+		t.Fatalf("unexpected output:\n%s\n%s", jsonl, txt)
+	}
+}
+
+// failWriter 第 n 次写入开始失败的输出器
+type failWriter struct {
+	n, writes int
+}
+
+func (w *failWriter) Write(item interface{}) error {
+	if w.writes++; w.writes >= w.n {
+		return errors.New("disk full")
+	}
+	return nil
+}
+
+func (w *failWriter) Close() error { return nil }
+
+func TestMultiStreamWriterFailure(t *testing.T) {
+	first, second := &failWriter{n: 2}, &failWriter{n: 4}
+	multi := NewMultiStreamWriter()
+	multi.Add("first", first, nil)
+	multi.Add("second", second, nil)
+
+	// 第一个输出器失败后被停用, 第二个输出器继续写入, 全部失败后才返回错误
+	for i := 1; i <= 4; i++ {
+		err := multi.Write(i)
+		if (err != nil) != (i == 4) {
+			t.Fatalf("write %d: unexpected error %v", i, err)
+		}
+		if i == 4 && (!strings.Contains(err.Error(), "first: disk full") || !strings.Contains(err.Error(), "second: disk full")) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if first.writes != 2 || second.writes != 4 {
+		t.Errorf("writes = %d/%d, want 2/4", first.writes, second.writes)
+	}
+}
+
+func TestMultiStreamWriterFailFast(t *testing.T) {
+	first, second := &failWriter{n: 2}, &failWriter{n: 4}
+	multi := NewMultiStreamWriter()
+	multi.FailFast = true
+	multi.Add("first", first, nil)
+	multi.Add("second", second, nil)
+
+	// 任一输出器失败都返回错误, 之后的写入同样返回错误
+	for i := 1; i <= 3; i++ {
+		err := multi.Write(i)
+		if (err != nil) != (i >= 2) {
+			t.Fatalf("write %d: unexpected error %v", i, err)
+		}
+		if i >= 2 && !strings.Contains(err.Error(), "first: disk full") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if err := multi.Sync(); err == nil {
+		t.Error("expected sync error after a failed output")
+	}
+	if first.writes != 2 || second.writes != 3 {
+		t.Errorf("writes = %d/%d, want 2/3", first.writes, second.writes)
+	}
+}
+
+func TestParseOutputSpec(t *testing.T) {
+	tests := map[string]OutputSpec{
+		"json:out/result.json":   {Type: "json", Path: "out/result.json"},
+		"CSV":                    {Type: "csv", Path: "result.csv"},
+		"sys:ignored":            {Type: "sys"},
+		`txt:C:\scan\result.txt`: {Type: "txt", Path: `C:\scan\result.txt`},
+	}
+	for value, want := range tests {
+		got, err := ParseOutputSpec(value)
+		if err != nil || got != want {
+			t.Errorf("%s: got %+v %v, want %+v", value, got, err, want)
+		}
+	}
+	if _, err := ParseOutputSpec(":result.json"); err == nil {
+		t.Error("expected error for missing type")
+	}
+}
@@ -0,0 +1,212 @@
+package fileutils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/winezer0/xutils/logging"
+)
+
+// webhook 默认配置
+const (
+	DefaultWebhookBatchSize = 100
+	DefaultWebhookBackoff   = time.Second
+	DefaultWebhookTimeout   = 30 * time.Second
+
+	// WebhookSignatureHeader 请求体的 HMAC-SHA256 签名, 形如 sha256=<hex>
+	WebhookSignatureHeader = "X-Cdninfo-Signature"
+	// WebhookBatchHeader 本次运行中批次的序号, 从 1 开始, 重试时不变, 可用于接收端去重
+	WebhookBatchHeader = "X-Cdninfo-Batch"
+)
+
+// WebhookOptions webhook 输出的配置
+type WebhookOptions struct {
+	Secret        string        // HMAC-SHA256 签名密钥, 为空时不签名
+	BatchSize     int           // 每次请求发送的结果数量, 默认 DefaultWebhookBatchSize
+	FlushInterval time.Duration // 未满的批次最多等待的时间, 为 0 时只在批次已满和关闭时发送
+	MaxRetries    int           // 网络错误、429 和 5xx 响应的重试次数
+	RetryBackoff  time.Duration // 首次重试前的等待时间, 之后每次翻倍, 默认 DefaultWebhookBackoff
+	Timeout       time.Duration // 单次请求的超时时间, 默认 DefaultWebhookTimeout
+	Client        *http.Client  // 为空时按 Timeout 创建
+}
+
+// webhookStreamWriter 将结果按批次以 JSON 数组 POST 到 webhook 地址
+// 发送和重试等待期间一直持有锁, 此时 Write 被阻塞, 推送变慢时流水线随之减速而不是无限缓存结果
+type webhookStreamWriter struct {
+	url     string
+	options WebhookOptions
+	client  *http.Client
+
+	mu    sync.Mutex
+	batch [][]byte
+	seq   int
+	err   error // 发送失败的错误, 之后的 Write、Sync 和 Close 都返回该错误
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewWebhookStreamWriter 创建 webhook 输出器, rawURL 必须为 http 或 https 地址
+func NewWebhookStreamWriter(rawURL string, options WebhookOptions) (StreamWriter, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid webhook url: %s", rawURL)
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultWebhookBatchSize
+	}
+	if options.RetryBackoff <= 0 {
+		options.RetryBackoff = DefaultWebhookBackoff
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultWebhookTimeout
+	}
+	client := options.Client
+	if client == nil {
+		client = &http.Client{Timeout: options.Timeout}
+	}
+
+	w := &webhookStreamWriter{url: rawURL, options: options, client: client}
+	if options.FlushInterval > 0 {
+		w.stop, w.done = make(chan struct{}), make(chan struct{})
+		go w.flushLoop()
+	}
+	return w, nil
+}
+
+func (w *webhookStreamWriter) Write(item interface{}) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	w.batch = append(w.batch, data)
+	if len(w.batch) >= w.options.BatchSize {
+		return w.flushLocked()
+	}
+	return nil
+}
+
+// Sync 立即发送当前未满的批次, 实现 SyncWriter 接口, 断点续扫时在记录完成状态前调用
+func (w *webhookStreamWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	return w.flushLocked()
+}
+
+// Close 发送剩余的结果
+func (w *webhookStreamWriter) Close() error {
+	if w.stop != nil {
+		close(w.stop)
+		<-w.done
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	return w.flushLocked()
+}
+
+// flushLoop 定时发送未满的批次, 使结果较少时也能及时送达
+func (w *webhookStreamWriter) flushLoop() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.options.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.err == nil {
+				_ = w.flushLocked()
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// flushLocked 发送当前批次, 调用方需持有锁
+// 收到 2xx 响应后才清空批次, 全部重试失败时保留批次并记录错误, 输出器不再可用
+// 重试之间的退避等待同样在持有锁时进行, 最长阻塞 Write 约 (MaxRetries+1) 次请求超时加上全部退避时间
+func (w *webhookStreamWriter) flushLocked() error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+	body := append([]byte{'['}, bytes.Join(w.batch, []byte{','})...)
+	body = append(body, ']')
+	seq := w.seq + 1
+
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(seq, body)
+		if err == nil {
+			logging.Debugf("Webhook batch %d with %d results sent", seq, len(w.batch))
+			w.seq = seq
+			w.batch = w.batch[:0]
+			return nil
+		}
+		if !retry || attempt >= w.options.MaxRetries {
+			w.err = fmt.Errorf("send webhook batch %d with %d results failed after %d attempts: %w", seq, len(w.batch), attempt+1, err)
+			return w.err
+		}
+		backoff := w.options.RetryBackoff << attempt
+		logging.Warnf("Send webhook batch %d failed, retry in %v: %v", seq, backoff, err)
+		time.Sleep(backoff)
+	}
+}
+
+// post 发送一次请求, 返回失败时是否可以重试
+func (w *webhookStreamWriter) post(seq int, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "cdninfo")
+	req.Header.Set(WebhookBatchHeader, strconv.Itoa(seq))
+	if w.options.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhookBody(w.options.Secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("unexpected status: %s", resp.Status)
+	default:
+		return false, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+}
+
+// SignWebhookBody 计算请求体的签名, 接收端使用相同的密钥计算后与 X-Cdninfo-Signature 比较
+func SignWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
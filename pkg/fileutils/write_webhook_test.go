@@ -0,0 +1,161 @@
+package fileutils
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookStreamWriter(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]map[string]string
+	var seqs []string
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(WebhookSignatureHeader) != SignWebhookBody("secret", body) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		// 第一次请求失败, 验证重试
+		if failures > 0 {
+			failures--
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		var batch []map[string]string
+		if err := json.Unmarshal(body, &batch); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		batches = append(batches, batch)
+		seqs = append(seqs, r.Header.Get(WebhookBatchHeader))
+	}))
+	defer server.Close()
+
+	writer, err := NewWebhookStreamWriter(server.URL, WebhookOptions{
+		Secret: "secret", BatchSize: 2, MaxRetries: 2, RetryBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fmtVal := range []string{"a.com", "b.com", "c.com"} {
+		if err := writer.Write(map[string]string{"fmt": fmtVal}); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	if len(batches) != 2 || len(batches[0]) != 2 || batches[1][0]["fmt"] != "c.com" {
+		t.Fatalf("unexpected batches: %v", batches)
+	}
+	if strings.Join(seqs, ",") != "1,2" {
+		t.Fatalf("unexpected batch numbers: %v", seqs)
+	}
+
+	// 签名错误等 4xx 响应不重试, 发送失败后输出器不再可用
+	writer, _ = NewWebhookStreamWriter(server.URL, WebhookOptions{Secret: "wrong", MaxRetries: 3, RetryBackoff: time.Millisecond})
+	_ = writer.Write(map[string]string{"fmt": "d.com"})
+	if err := writer.(SyncWriter).Sync(); err == nil || !strings.Contains(err.Error(), "batch 1 with 1 results failed after 1 attempts") {
+		t.Fatalf("expected error without retry, got %v", err)
+	}
+	if err := writer.Write(map[string]string{"fmt": "e.com"}); err == nil {
+		t.Fatal("expected write error after a failed batch")
+	}
+	if err := writer.Close(); err == nil {
+		t.Fatal("expected close error after a failed batch")
+	}
+
+	if _, err := NewWebhookStreamWriter("ftp://example.com", WebhookOptions{}); err == nil {
+		t.Fatal("expected error for non http url")
+	}
+}
+
+func TestWebhookStreamWriterSync(t *testing.T) {
+	var mu sync.Mutex
+	var sizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []json.RawMessage
+		_ = json.NewDecoder(r.Body).Decode(&batch)
+		mu.Lock()
+		sizes = append(sizes, len(batch))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	writer, err := NewWebhookStreamWriter(server.URL, WebhookOptions{BatchSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 断点续扫时记录完成状态前调用 Sync, 未满的批次立即发送
+	for _, fmtVal := range []string{"a.com", "b.com"} {
+		if err := writer.Write(map[string]string{"fmt": fmtVal}); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.(SyncWriter).Sync(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mu.Lock()
+	got := append([]int(nil), sizes...)
+	mu.Unlock()
+	if len(got) != 2 || got[0] != 1 || got[1] != 1 {
+		t.Fatalf("unexpected batch sizes after sync: %v", got)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != 2 {
+		t.Fatalf("close should not send an empty batch: %v", sizes)
+	}
+}
+
+type memoryPublisher struct {
+	target   string
+	messages map[string]string
+	closed   bool
+}
+
+func (p *memoryPublisher) Publish(key string, value []byte) error {
+	p.messages[key] = string(value)
+	return nil
+}
+
+func (p *memoryPublisher) Close() error {
+	p.closed = true
+	return nil
+}
+
+func TestPublisherStreamWriter(t *testing.T) {
+	publisher := &memoryPublisher{messages: make(map[string]string)}
+	RegisterPublisher("memory", func(target string) (Publisher, error) {
+		publisher.target = target
+		return publisher, nil
+	})
+	if !IsRemoteOutput("memory") || IsRemoteOutput("csv") {
+		t.Fatal("unexpected remote output types")
+	}
+
+	type row struct {
+		FMT   string `json:"fmt"`
+		IsCdn bool   `json:"is_cdn"`
+	}
+	if err := WriteOutputToFile([]row{{FMT: "a.com", IsCdn: true}, {FMT: "b.com"}}, "memory", "broker:9092/results"); err != nil {
+		t.Fatal(err)
+	}
+	if publisher.target != "broker:9092/results" || !publisher.closed {
+		t.Fatalf("unexpected publisher state: %+v", publisher)
+	}
+	if publisher.messages["a.com"] != `{"fmt":"a.com","is_cdn":true}` || len(publisher.messages) != 2 {
+		t.Fatalf("unexpected messages: %v", publisher.messages)
+	}
+}
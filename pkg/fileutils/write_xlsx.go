@@ -0,0 +1,328 @@
+package fileutils
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// xlsxMaxSheetName Excel 工作表名称的最大长度
+const xlsxMaxSheetName = 31
+
+// xlsxMaxCellText Excel 单元格文本的最大长度, 按 UTF-16 编码单元计算
+const xlsxMaxCellText = 32767
+
+// xlsxMaxRows Excel 工作表的最大行数, 超过时拆分为多个工作表
+var xlsxMaxRows = 1048576
+
+// XlsxSheet 工作表，第一行作为表头加粗并冻结
+type XlsxSheet struct {
+	Name string
+	Rows [][]interface{}
+}
+
+// XlsxWorkbook 不依赖第三方库的简单 xlsx 工作簿，只支持文本、数字和布尔单元格
+type XlsxWorkbook struct {
+	Sheets []*XlsxSheet
+}
+
+// AddSheet 添加工作表，名称中的非法字符会被替换，重名时自动添加序号
+func (wb *XlsxWorkbook) AddSheet(name string) *XlsxSheet {
+	sheet := &XlsxSheet{Name: uniqueSheetName(name, wb.Sheets)}
+	wb.Sheets = append(wb.Sheets, sheet)
+	return sheet
+}
+
+// uniqueSheetName 生成合法且不与 sheets 重名的工作表名称
+func uniqueSheetName(name string, sheets ...[]*XlsxSheet) string {
+	name = strings.NewReplacer("[", "(", "]", ")", ":", "_", "*", "_", "?", "_", "/", "_", "\\", "_").Replace(name)
+	if name == "" {
+		name = "Sheet"
+	}
+	base := truncateRunes(name, xlsxMaxSheetName)
+	name = base
+	for i := 2; hasSheet(name, sheets...); i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		name = truncateRunes(base, xlsxMaxSheetName-len(suffix)) + suffix
+	}
+	return name
+}
+
+func hasSheet(name string, sheets ...[]*XlsxSheet) bool {
+	for _, list := range sheets {
+		for _, sheet := range list {
+			if strings.EqualFold(sheet.Name, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitSheets 将超过 Excel 行数上限的工作表拆分为多个相邻的工作表, 后续工作表重复第一行表头
+func (wb *XlsxWorkbook) splitSheets() {
+	origin := wb.Sheets
+	wb.Sheets = make([]*XlsxSheet, 0, len(origin))
+	for _, sheet := range origin {
+		wb.Sheets = append(wb.Sheets, sheet)
+		if len(sheet.Rows) <= xlsxMaxRows {
+			continue
+		}
+		header, rest := sheet.Rows[0], sheet.Rows[xlsxMaxRows:]
+		sheet.Rows = sheet.Rows[:xlsxMaxRows]
+		for len(rest) > 0 {
+			n := min(len(rest), xlsxMaxRows-1)
+			part := &XlsxSheet{Name: uniqueSheetName(sheet.Name, wb.Sheets, origin)}
+			part.Rows = append([][]interface{}{header}, rest[:n]...)
+			wb.Sheets = append(wb.Sheets, part)
+			rest = rest[n:]
+		}
+	}
+}
+
+// AddRow 追加一行数据
+func (s *XlsxSheet) AddRow(values ...interface{}) {
+	s.Rows = append(s.Rows, values)
+}
+
+// Save 将工作簿写入文件
+func (wb *XlsxWorkbook) Save(filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	if err := wb.write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (wb *XlsxWorkbook) write(file *os.File) error {
+	if len(wb.Sheets) == 0 {
+		wb.AddSheet("Sheet1")
+	}
+	wb.splitSheets()
+
+	zw := zip.NewWriter(file)
+	parts := map[string]string{
+		"[Content_Types].xml":        wb.contentTypes(),
+		"_rels/.rels":                xlsxRootRels,
+		"xl/workbook.xml":            wb.workbook(),
+		"xl/_rels/workbook.xml.rels": wb.workbookRels(),
+		"xl/styles.xml":              xlsxStyles,
+	}
+	order := []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"}
+	for i, sheet := range wb.Sheets {
+		name := fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1)
+		parts[name] = sheet.xml()
+		order = append(order, name)
+	}
+
+	for _, name := range order {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte(parts[name])); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+const xlsxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+const xlsxRootRels = xlsxHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// xlsxStyles 样式 0 为默认, 样式 1 为加粗表头
+const xlsxStyles = xlsxHeader + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+	`</styleSheet>`
+
+func (wb *XlsxWorkbook) contentTypes() string {
+	var sb strings.Builder
+	sb.WriteString(xlsxHeader)
+	sb.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	sb.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	sb.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	sb.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	sb.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range wb.Sheets {
+		sb.WriteString(fmt.Sprintf(`<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1))
+	}
+	sb.WriteString(`</Types>`)
+	return sb.String()
+}
+
+func (wb *XlsxWorkbook) workbook() string {
+	var sb strings.Builder
+	sb.WriteString(xlsxHeader)
+	sb.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, sheet := range wb.Sheets {
+		sb.WriteString(fmt.Sprintf(`<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sheet.Name), i+1, i+1))
+	}
+	sb.WriteString(`</sheets></workbook>`)
+	return sb.String()
+}
+
+func (wb *XlsxWorkbook) workbookRels() string {
+	var sb strings.Builder
+	sb.WriteString(xlsxHeader)
+	sb.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range wb.Sheets {
+		sb.WriteString(fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1))
+	}
+	styleID := len(wb.Sheets) + 1
+	sb.WriteString(fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, styleID))
+	sb.WriteString(`</Relationships>`)
+	return sb.String()
+}
+
+func (s *XlsxSheet) xml() string {
+	var sb strings.Builder
+	sb.WriteString(xlsxHeader)
+	sb.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(s.Rows) > 1 {
+		sb.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+
+	// 按内容估算列宽
+	var widths []int
+	for _, row := range s.Rows {
+		for i, value := range row {
+			for len(widths) <= i {
+				widths = append(widths, 8)
+			}
+			if width := displayWidth(xlsxCellText(value)) + 2; width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+	if len(widths) > 0 {
+		sb.WriteString(`<cols>`)
+		for i, width := range widths {
+			if width > 60 {
+				width = 60
+			}
+			sb.WriteString(fmt.Sprintf(`<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, width))
+		}
+		sb.WriteString(`</cols>`)
+	}
+
+	sb.WriteString(`<sheetData>`)
+	for r, row := range s.Rows {
+		sb.WriteString(fmt.Sprintf(`<row r="%d">`, r+1))
+		for c, value := range row {
+			ref := XlsxColumnName(c) + strconv.Itoa(r+1)
+			style := ""
+			if r == 0 {
+				style = ` s="1"`
+			}
+			sb.WriteString(xlsxCell(ref, style, value))
+		}
+		sb.WriteString(`</row>`)
+	}
+	sb.WriteString(`</sheetData></worksheet>`)
+	return sb.String()
+}
+
+// xlsxCell 生成单元格 XML, 数字和布尔值保持原类型以便在 Excel 中计算和筛选
+func xlsxCell(ref, style string, value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return fmt.Sprintf(`<c r="%s"%s/>`, ref, style)
+	case bool:
+		b := 0
+		if v {
+			b = 1
+		}
+		return fmt.Sprintf(`<c r="%s"%s t="b"><v>%d</v></c>`, ref, style, b)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprintf(`<c r="%s"%s><v>%v</v></c>`, ref, style, v)
+	default:
+		return fmt.Sprintf(`<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, xmlEscape(xlsxCellText(v)))
+	}
+}
+
+// xlsxCellText 单元格文本, 超过 Excel 单元格长度上限时截断
+func xlsxCellText(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	s, ok := value.(string)
+	if !ok {
+		s = fmt.Sprint(value)
+	}
+	if len(s) <= xlsxMaxCellText {
+		return s
+	}
+	units := 0
+	for i, r := range s {
+		if units += utf16.RuneLen(r); units > xlsxMaxCellText {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// XlsxColumnName 将从 0 开始的列序号转换为 A、B ... AA 形式的列名
+func XlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// xmlEscape 转义 XML 特殊字符并去除 XML 不允许的控制字符和 U+FFFE/U+FFFF
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '&':
+			sb.WriteString("&amp;")
+		case r == '<':
+			sb.WriteString("&lt;")
+		case r == '>':
+			sb.WriteString("&gt;")
+		case r == '"':
+			sb.WriteString("&quot;")
+		case r == 0xFFFE || r == 0xFFFF:
+		case r == '\t' || r == '\n' || r == '\r' || r >= 0x20:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// displayWidth 估算显示宽度, 中文等宽字符按两个字符计算
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		if r > 0x2E80 {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
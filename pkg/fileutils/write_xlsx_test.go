@@ -0,0 +1,47 @@
+package fileutils
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestXlsxCellLimits(t *testing.T) {
+	long := strings.Repeat("a", xlsxMaxCellText-1) + "中文"
+	if got := xlsxCellText(long); len(utf16.Encode([]rune(got))) != xlsxMaxCellText || !strings.HasSuffix(got, "a中") {
+		t.Errorf("cell not truncated to %d characters: ...%q", xlsxMaxCellText, got[len(got)-8:])
+	}
+	if got := xlsxCellText(strings.Repeat("😀", xlsxMaxCellText)); len(utf16.Encode([]rune(got))) > xlsxMaxCellText {
+		t.Errorf("cell with surrogate pairs exceeds %d characters", xlsxMaxCellText)
+	}
+	if got := xmlEscape("a\uFFFEb\uFFFF<\x01"); got != "ab&lt;" {
+		t.Errorf("xmlEscape = %q", got)
+	}
+}
+
+func TestXlsxSplitSheets(t *testing.T) {
+	defer func(rows int) { xlsxMaxRows = rows }(xlsxMaxRows)
+	xlsxMaxRows = 3
+
+	wb := &XlsxWorkbook{}
+	results := wb.AddSheet("Results")
+	results.AddRow("fmt")
+	for _, value := range []string{"a", "b", "c", "d", "e"} {
+		results.AddRow(value)
+	}
+	wb.AddSheet("Results (2)").AddRow("other")
+	wb.splitSheets()
+
+	var got []string
+	for _, sheet := range wb.Sheets {
+		var rows []string
+		for _, row := range sheet.Rows {
+			rows = append(rows, row[0].(string))
+		}
+		got = append(got, sheet.Name+":"+strings.Join(rows, ","))
+	}
+	want := "Results:fmt,a,b|Results (3):fmt,c,d|Results (4):fmt,e|Results (2):other"
+	if strings.Join(got, "|") != want {
+		t.Errorf("sheets = %s, want %s", strings.Join(got, "|"), want)
+	}
+}
@@ -0,0 +1,83 @@
+package inputs
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Target 输入目标及其附带的额外字段
+type Target struct {
+	Value string            // 主机、IP 或 URL
+	Extra map[string]string // 输入中的其他字段，如资产负责人、端口，原样带到输出结果中
+}
+
+// ReadFunc 从 reader 中解析目标并逐个发送到 out，返回发送的目标数量，不关闭 out
+// arg 为输入类型中冒号后的参数，如 csv:host 中的 host
+type ReadFunc func(reader io.Reader, arg string, out chan<- Target) (int, error)
+
+var (
+	mu      sync.RWMutex
+	readers = map[string]ReadFunc{}
+)
+
+// Register 注册输入格式的解析函数，同名格式会被覆盖
+func Register(name string, fn ReadFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+	readers[strings.ToLower(name)] = fn
+}
+
+// Formats 返回已注册的输入格式名称
+func Formats() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(readers))
+	for name := range readers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseType 将 csv:host 形式的输入类型拆分为格式名称和参数
+func ParseType(inputType string) (string, string) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(inputType), ":")
+	return strings.ToLower(name), strings.TrimSpace(arg)
+}
+
+// IsFormat 判断输入类型是否为已注册的格式
+func IsFormat(inputType string) bool {
+	name, _ := ParseType(inputType)
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok := readers[name]
+	return ok
+}
+
+// Read 按输入类型解析 reader 中的目标
+func Read(inputType string, reader io.Reader, out chan<- Target) (int, error) {
+	name, arg := ParseType(inputType)
+	mu.RLock()
+	fn, ok := readers[name]
+	mu.RUnlock()
+	if !ok {
+		return 0, fmt.Errorf("unsupported input format: %s (support: %s)", name, strings.Join(Formats(), "/"))
+	}
+	return fn(reader, arg, out)
+}
+
+// send 发送非空目标，返回是否发送
+func send(out chan<- Target, value string, extra map[string]string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false
+	}
+	if len(extra) == 0 {
+		extra = nil
+	}
+	out <- Target{Value: value, Extra: extra}
+	return true
+}
@@ -0,0 +1,116 @@
+package inputs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readAll(t *testing.T, inputType, data string) []Target {
+	t.Helper()
+	out := make(chan Target, 100)
+	count, err := Read(inputType, strings.NewReader(data), out)
+	close(out)
+	if err != nil {
+		t.Fatalf("%s: %v", inputType, err)
+	}
+
+	var targets []Target
+	for target := range out {
+		targets = append(targets, target)
+	}
+	if count != len(targets) {
+		t.Errorf("%s: count = %d, want %d", inputType, count, len(targets))
+	}
+	return targets
+}
+
+func TestReadCSV(t *testing.T) {
+	data := "owner,Host,port\nalice,a.com,443\nbob,,80\ncarol,b.com,\n"
+	got := readAll(t, "csv:host", data)
+	want := []Target{
+		{Value: "a.com", Extra: map[string]string{"owner": "alice", "port": "443"}},
+		{Value: "b.com", Extra: map[string]string{"owner": "carol"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if got := readAll(t, "csv:2", data); len(got) != 2 || got[0].Value != "a.com" {
+		t.Errorf("csv by index got %+v", got)
+	}
+}
+
+func TestReadJSONL(t *testing.T) {
+	data := `{"host":"a.com","source":"crtsh","meta":{"ip":"1.1.1.1"}}
+not json
+{"host":["b.com","c.com"],"port":8443}
+{"other":"x"}
+{"host":"d.com","id":12345678901234567890,"ratio":0.5,"ok":true}
+{"host":"e.com"} trailing
+`
+	got := readAll(t, "jsonl", data)
+	want := []Target{
+		{Value: "a.com", Extra: map[string]string{"source": "crtsh"}},
+		{Value: "b.com", Extra: map[string]string{"port": "8443"}},
+		{Value: "c.com", Extra: map[string]string{"port": "8443"}},
+		{Value: "d.com", Extra: map[string]string{"id": "12345678901234567890", "ratio": "0.5", "ok": "true"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if got := readAll(t, "jsonl:meta.ip", data); len(got) != 1 || got[0].Value != "1.1.1.1" {
+		t.Errorf("nested field got %+v", got)
+	}
+}
+
+func TestReadNmapXML(t *testing.T) {
+	data := `<?xml version="1.0"?>
+<nmaprun>
+<host><address addr="1.2.3.4" addrtype="ipv4"/><hostnames><hostname name="a.com" type="user"/><hostname name="ptr.a.com" type="PTR"/></hostnames>
+<ports><port protocol="tcp" portid="80"><state state="open"/><service name="http"/></port><port protocol="tcp" portid="22"><state state="closed"/></port></ports></host>
+<host><address addr="5.6.7.8" addrtype="ipv4"/><ports><port protocol="tcp" portid="443"><state state="open"/></port></ports></host>
+</nmaprun>`
+	got := readAll(t, "nmap", data)
+	want := []Target{
+		{Value: "a.com", Extra: map[string]string{"ip": "1.2.3.4", "ports": "80/tcp", "services": "80/http"}},
+		{Value: "5.6.7.8", Extra: map[string]string{"ip": "5.6.7.8", "ports": "443/tcp"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestReadHARAndBurp(t *testing.T) {
+	har := `{"log":{"entries":[{"serverIPAddress":"1.1.1.1","request":{"method":"GET","url":"https://a.com/x"},"response":{"status":200}}]}}`
+	got := readAll(t, "har", har)
+	want := []Target{{Value: "https://a.com/x", Extra: map[string]string{"method": "GET", "status": "200", "ip": "1.1.1.1"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("har got %+v, want %+v", got, want)
+	}
+
+	burp := `<items><item><url><![CDATA[https://b.com/login]]></url><host ip="2.2.2.2">b.com</host><port>443</port><method>POST</method><status>302</status></item></items>`
+	got = readAll(t, "burp", burp)
+	want = []Target{{Value: "https://b.com/login", Extra: map[string]string{"ip": "2.2.2.2", "port": "443", "method": "POST", "status": "302"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("burp got %+v, want %+v", got, want)
+	}
+}
+
+func TestReadUnknownFormat(t *testing.T) {
+	if IsFormat("xml") {
+		t.Errorf("xml should not be a registered format")
+	}
+	if _, err := Read("xml", strings.NewReader(""), make(chan Target)); err == nil {
+		t.Errorf("expected error for unknown format")
+	}
+}
+
+func TestReadLines(t *testing.T) {
+	got := readAll(t, "lines", "a.com\n\n# comment\n  b.com  \n")
+	want := []Target{{Value: "a.com"}, {Value: "b.com"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+package inputs
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	Register("burp", ReadBurpXML)
+}
+
+// burpItem Burp Suite 导出 XML 中的 item 节点
+type burpItem struct {
+	URL  string `xml:"url"`
+	Host struct {
+		Name string `xml:",chardata"`
+		IP   string `xml:"ip,attr"`
+	} `xml:"host"`
+	Port   string `xml:"port"`
+	Method string `xml:"method"`
+	Status string `xml:"status"`
+}
+
+// ReadBurpXML 读取 Burp Suite 导出的 HTTP 历史 XML，每个请求的 URL 作为目标
+func ReadBurpXML(reader io.Reader, _ string, out chan<- Target) (int, error) {
+	decoder := xml.NewDecoder(reader)
+	count := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, fmt.Errorf("failed to parse burp xml: %w", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "item" {
+			continue
+		}
+		var item burpItem
+		if err := decoder.DecodeElement(&item, &start); err != nil {
+			return count, fmt.Errorf("failed to parse burp item: %w", err)
+		}
+
+		value := strings.TrimSpace(item.URL)
+		if value == "" {
+			value = item.Host.Name
+		}
+		extra := map[string]string{}
+		for key, v := range map[string]string{"ip": item.Host.IP, "port": item.Port, "method": item.Method, "status": item.Status} {
+			if v = strings.TrimSpace(v); v != "" {
+				extra[key] = v
+			}
+		}
+		if send(out, value, extra) {
+			count++
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package inputs
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register("csv", ReadCSV)
+}
+
+// ReadCSV 读取带表头的 CSV，column 为目标所在列的列名或从 1 开始的序号，默认第一列
+// 其他列作为额外字段
+func ReadCSV(reader io.Reader, column string, out chan<- Target) (int, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+
+	headers, err := csvReader.Read()
+	if err == io.EOF {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	for i := range headers {
+		headers[i] = strings.TrimSpace(strings.TrimPrefix(headers[i], "\ufeff"))
+	}
+
+	index, err := columnIndex(headers, column)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, fmt.Errorf("failed to read csv row: %w", err)
+		}
+		if index >= len(record) {
+			continue
+		}
+
+		extra := make(map[string]string)
+		for i, value := range record {
+			if i != index && i < len(headers) && value != "" {
+				extra[headers[i]] = value
+			}
+		}
+		if send(out, record[index], extra) {
+			count++
+		}
+	}
+}
+
+// columnIndex 根据列名(不区分大小写)或序号查找列
+func columnIndex(headers []string, column string) (int, error) {
+	if column == "" {
+		return 0, nil
+	}
+	for i, header := range headers {
+		if strings.EqualFold(header, column) {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(column); err == nil && n >= 1 && n <= len(headers) {
+		return n - 1, nil
+	}
+	return 0, fmt.Errorf("csv column not found: %s (headers: %s)", column, strings.Join(headers, ","))
+}
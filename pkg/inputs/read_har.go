@@ -0,0 +1,51 @@
+package inputs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+func init() {
+	Register("har", ReadHAR)
+}
+
+// harFile HAR 文件中需要的字段
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			ServerIPAddress string `json:"serverIPAddress"`
+			Request         struct {
+				Method string `json:"method"`
+				URL    string `json:"url"`
+			} `json:"request"`
+			Response struct {
+				Status int `json:"status"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// ReadHAR 读取浏览器或代理工具导出的 HAR 文件，每个请求的 URL 作为目标
+func ReadHAR(reader io.Reader, _ string, out chan<- Target) (int, error) {
+	var har harFile
+	if err := json.NewDecoder(reader).Decode(&har); err != nil {
+		return 0, fmt.Errorf("failed to parse har: %w", err)
+	}
+
+	count := 0
+	for _, entry := range har.Log.Entries {
+		extra := map[string]string{"method": entry.Request.Method}
+		if entry.Response.Status > 0 {
+			extra["status"] = strconv.Itoa(entry.Response.Status)
+		}
+		if entry.ServerIPAddress != "" {
+			extra["ip"] = entry.ServerIPAddress
+		}
+		if send(out, entry.Request.URL, extra) {
+			count++
+		}
+	}
+	return count, nil
+}
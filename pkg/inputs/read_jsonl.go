@@ -0,0 +1,118 @@
+package inputs
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/winezer0/xutils/logging"
+)
+
+func init() {
+	Register("jsonl", ReadJSONL)
+}
+
+// ReadJSONL 逐行读取 JSON 对象，field 为目标所在字段，支持 a.b 形式的嵌套字段，默认 host
+// 字段值为数组时每个元素作为一个目标，其他顶层标量字段作为额外字段
+func ReadJSONL(reader io.Reader, field string, out chan<- Target) (int, error) {
+	if field == "" {
+		field = "host"
+	}
+	path := strings.Split(field, ".")
+
+	count := 0
+	lineNum := 0
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		// 数字保留原始文本, 避免较大的 id、端口等字段转换为 1e+06 形式
+		var object map[string]interface{}
+		decoder := json.NewDecoder(strings.NewReader(line))
+		decoder.UseNumber()
+		err := decoder.Decode(&object)
+		if err == nil {
+			if _, tokenErr := decoder.Token(); tokenErr != io.EOF {
+				err = fmt.Errorf("invalid data after top-level value")
+			}
+		}
+		if err != nil {
+			logging.Warnf("Skip invalid json line %d: %v", lineNum, err)
+			continue
+		}
+
+		value := lookupField(object, path)
+		if value == nil {
+			logging.Debugf("Field [%s] not found in json line %d", field, lineNum)
+			continue
+		}
+
+		extra := make(map[string]string)
+		for key, v := range object {
+			if key == path[0] {
+				continue
+			}
+			if s, ok := scalarString(v); ok && s != "" {
+				extra[key] = s
+			}
+		}
+
+		for _, s := range valueStrings(value) {
+			if send(out, s, extra) {
+				count++
+			}
+		}
+	}
+	return count, scanner.Err()
+}
+
+// lookupField 按路径查找嵌套字段
+func lookupField(object map[string]interface{}, path []string) interface{} {
+	var current interface{} = object
+	for _, key := range path {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
+
+// valueStrings 将字段值转换为目标列表
+func valueStrings(value interface{}) []string {
+	if list, ok := value.([]interface{}); ok {
+		var values []string
+		for _, item := range list {
+			if s, ok := scalarString(item); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	if s, ok := scalarString(value); ok {
+		return []string{s}
+	}
+	return nil
+}
+
+// scalarString 将 JSON 标量转换为字符串
+func scalarString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return fmt.Sprint(v), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,40 @@
+package inputs
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+func init() {
+	Register("lines", ReadLines)
+}
+
+// ReadLines 逐行读取目标，忽略空行和 # 开头的注释行
+func ReadLines(reader io.Reader, _ string, out chan<- Target) (int, error) {
+	count := 0
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if send(out, line, nil) {
+			count++
+		}
+	}
+	return count, scanner.Err()
+}
+
+// ReadList 读取逗号分隔的目标列表
+func ReadList(list string, out chan<- Target) int {
+	count := 0
+	for _, value := range strings.Split(list, ",") {
+		if send(out, value, nil) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,100 @@
+package inputs
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	Register("nmap", ReadNmapXML)
+	Register("masscan", ReadNmapXML)
+}
+
+// nmapHost nmap/masscan XML 输出中的 host 节点
+type nmapHost struct {
+	Addresses []struct {
+		Addr     string `xml:"addr,attr"`
+		AddrType string `xml:"addrtype,attr"`
+	} `xml:"address"`
+	Hostnames []struct {
+		Name string `xml:"name,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"hostnames>hostname"`
+	Ports []struct {
+		Protocol string `xml:"protocol,attr"`
+		PortID   string `xml:"portid,attr"`
+		State    struct {
+			State string `xml:"state,attr"`
+		} `xml:"state"`
+		Service struct {
+			Name string `xml:"name,attr"`
+		} `xml:"service"`
+	} `xml:"ports>port"`
+}
+
+// ReadNmapXML 读取 nmap -oX 或 masscan -oX 的输出
+// 优先使用扫描时指定的主机名(type=user)作为目标，否则使用IP地址，开放端口作为额外字段
+func ReadNmapXML(reader io.Reader, _ string, out chan<- Target) (int, error) {
+	decoder := xml.NewDecoder(reader)
+	count := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, fmt.Errorf("failed to parse nmap xml: %w", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "host" {
+			continue
+		}
+		var host nmapHost
+		if err := decoder.DecodeElement(&host, &start); err != nil {
+			return count, fmt.Errorf("failed to parse nmap host: %w", err)
+		}
+
+		extra := make(map[string]string)
+		var ip string
+		for _, address := range host.Addresses {
+			if address.AddrType == "ipv4" || address.AddrType == "ipv6" {
+				ip = address.Addr
+				break
+			}
+		}
+		if ip != "" {
+			extra["ip"] = ip
+		}
+
+		var ports, services []string
+		for _, port := range host.Ports {
+			if port.State.State != "open" {
+				continue
+			}
+			ports = append(ports, port.PortID+"/"+port.Protocol)
+			if port.Service.Name != "" {
+				services = append(services, port.PortID+"/"+port.Service.Name)
+			}
+		}
+		if len(ports) > 0 {
+			extra["ports"] = strings.Join(ports, ",")
+		}
+		if len(services) > 0 {
+			extra["services"] = strings.Join(services, ",")
+		}
+
+		value := ip
+		for _, hostname := range host.Hostnames {
+			if hostname.Type == "user" {
+				value = hostname.Name
+				break
+			}
+		}
+		if send(out, value, extra) {
+			count++
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package progress
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// QueryStats 某个解析器或城市的查询统计
+type QueryStats struct {
+	Sent     int64 `json:"sent"`
+	Failed   int64 `json:"failed"`
+	TimedOut int64 `json:"timed_out"`
+}
+
+// Stats 一次运行的统计信息，所有方法均可并发调用
+// 各查询模块通过配置接收 Stats, 为 nil 时 Record 系列方法不做任何记录
+type Stats struct {
+	startTime time.Time
+
+	targetsRead    atomic.Int64
+	targetsSkipped atomic.Int64
+	targetsDup     atomic.Int64
+	targetsDone    atomic.Int64
+	ipLookups      atomic.Int64
+	ipLookupFailed atomic.Int64
+
+	mu          sync.Mutex
+	categories  map[string]int64
+	dnsQueries  map[string]*QueryStats
+	ednsQueries map[string]*QueryStats
+	hits        map[string]map[string]int64
+}
+
+// Snapshot 统计信息快照，用于状态行显示和 stats.json 输出
+type Snapshot struct {
+	StartTime      string                      `json:"start_time"`
+	ElapsedSeconds float64                     `json:"elapsed_seconds"`
+	TargetsRead    int64                       `json:"targets_read"`
+	TargetsSkipped int64                       `json:"targets_skipped"`
+	TargetsDup     int64                       `json:"targets_duplicate"`
+	TargetsDone    int64                       `json:"targets_done"`
+	Categories     map[string]int64            `json:"categories"`
+	DNSQueries     map[string]QueryStats       `json:"dns_queries"`
+	EDNSQueries    map[string]QueryStats       `json:"edns_queries"`
+	IPLookups      int64                       `json:"ip_lookups"`
+	IPLookupFailed int64                       `json:"ip_lookup_failed"`
+	Hits           map[string]map[string]int64 `json:"hits"`
+}
+
+// NewStats 创建统计对象，开始计时
+func NewStats() *Stats {
+	return &Stats{
+		startTime:   time.Now(),
+		categories:  make(map[string]int64),
+		dnsQueries:  make(map[string]*QueryStats),
+		ednsQueries: make(map[string]*QueryStats),
+		hits:        make(map[string]map[string]int64),
+	}
+}
+
+// RecordTarget 记录一个已分类的目标
+func (s *Stats) RecordTarget(category string) {
+	if s == nil {
+		return
+	}
+	s.targetsRead.Add(1)
+	s.mu.Lock()
+	s.categories[category]++
+	s.mu.Unlock()
+}
+
+// RecordSkipped 记录一个被跳过的目标
+func (s *Stats) RecordSkipped() {
+	if s == nil {
+		return
+	}
+	s.targetsSkipped.Add(1)
+}
+
+// RecordDuplicate 记录一个与已有目标 FMT 相同、复用其结果的目标
+func (s *Stats) RecordDuplicate() {
+	if s == nil {
+		return
+	}
+	s.targetsDup.Add(1)
+}
+
+// RecordDone 记录一个完成分析的目标
+func (s *Stats) RecordDone() {
+	if s == nil {
+		return
+	}
+	s.targetsDone.Add(1)
+}
+
+// RecordDNSQuery 记录一次对 resolver 的DNS查询
+func (s *Stats) RecordDNSQuery(resolver string, err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	recordQuery(s.dnsQueries, resolver, err)
+	s.mu.Unlock()
+}
+
+// RecordEDNSQuery 记录一次按城市进行的EDNS查询
+func (s *Stats) RecordEDNSQuery(city string, err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	recordQuery(s.ednsQueries, city, err)
+	s.mu.Unlock()
+}
+
+// RecordIPLookup 记录一次IP数据库查询
+func (s *Stats) RecordIPLookup(err error) {
+	if s == nil {
+		return
+	}
+	s.ipLookups.Add(1)
+	if err != nil {
+		s.ipLookupFailed.Add(1)
+	}
+}
+
+// RecordHit 记录一次分析命中，category 如 cdn/waf/cloud，company 为命中的厂商
+func (s *Stats) RecordHit(category, company string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.hits[category] == nil {
+		s.hits[category] = make(map[string]int64)
+	}
+	s.hits[category][company]++
+	s.mu.Unlock()
+}
+
+func recordQuery(queries map[string]*QueryStats, key string, err error) {
+	stats, ok := queries[key]
+	if !ok {
+		stats = &QueryStats{}
+		queries[key] = stats
+	}
+	stats.Sent++
+	if err != nil {
+		if IsTimeout(err) {
+			stats.TimedOut++
+		} else {
+			stats.Failed++
+		}
+	}
+}
+
+// IsTimeout 判断错误是否为超时
+func IsTimeout(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	// 部分查询结果仅保留了错误文本
+	return strings.Contains(strings.ToLower(err.Error()), "timeout")
+}
+
+// Snapshot 获取当前统计快照
+func (s *Stats) Snapshot() Snapshot {
+	snapshot := Snapshot{
+		StartTime:      s.startTime.Format(time.RFC3339),
+		ElapsedSeconds: time.Since(s.startTime).Seconds(),
+		TargetsRead:    s.targetsRead.Load(),
+		TargetsSkipped: s.targetsSkipped.Load(),
+		TargetsDup:     s.targetsDup.Load(),
+		TargetsDone:    s.targetsDone.Load(),
+		IPLookups:      s.ipLookups.Load(),
+		IPLookupFailed: s.ipLookupFailed.Load(),
+		Categories:     make(map[string]int64),
+		DNSQueries:     make(map[string]QueryStats),
+		EDNSQueries:    make(map[string]QueryStats),
+		Hits:           make(map[string]map[string]int64),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range s.categories {
+		snapshot.Categories[k] = v
+	}
+	for k, v := range s.dnsQueries {
+		snapshot.DNSQueries[k] = *v
+	}
+	for k, v := range s.ednsQueries {
+		snapshot.EDNSQueries[k] = *v
+	}
+	for category, companies := range s.hits {
+		snapshot.Hits[category] = make(map[string]int64)
+		for company, count := range companies {
+			snapshot.Hits[category][company] = count
+		}
+	}
+	return snapshot
+}
+
+// TotalQueries 汇总所有 key 的查询统计
+func TotalQueries(queries map[string]QueryStats) QueryStats {
+	var total QueryStats
+	for _, q := range queries {
+		total.Sent += q.Sent
+		total.Failed += q.Failed
+		total.TimedOut += q.TimedOut
+	}
+	return total
+}
+
+// TotalHits 汇总某个分类下所有厂商的命中数
+func TotalHits(companies map[string]int64) int64 {
+	var total int64
+	for _, count := range companies {
+		total += count
+	}
+	return total
+}
+
+// sortedKeys 返回排序后的 map 键
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,46 @@
+package progress
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestStatsSnapshot(t *testing.T) {
+	stats := NewStats()
+	stats.RecordTarget("Domain")
+	stats.RecordTarget("IP")
+	stats.RecordDNSQuery("8.8.8.8", nil)
+	stats.RecordDNSQuery("8.8.8.8", os.ErrDeadlineExceeded)
+	stats.RecordDNSQuery("1.1.1.1", errors.New("connection refused"))
+	stats.RecordEDNSQuery("Beijing", errors.New("read udp: i/o timeout"))
+	stats.RecordHit("cdn", "cloudflare")
+
+	s := stats.Snapshot()
+	if s.TargetsRead != 2 || s.Categories["IP"] != 1 {
+		t.Fatalf("unexpected targets: %+v", s)
+	}
+	if q := s.DNSQueries["8.8.8.8"]; q.Sent != 2 || q.TimedOut != 1 || q.Failed != 0 {
+		t.Fatalf("unexpected resolver stats: %+v", q)
+	}
+	if total := TotalQueries(s.DNSQueries); total.Sent != 3 || total.Failed != 1 {
+		t.Fatalf("unexpected total stats: %+v", total)
+	}
+	if q := s.EDNSQueries["Beijing"]; q.TimedOut != 1 {
+		t.Fatalf("unexpected edns stats: %+v", q)
+	}
+	if TotalHits(s.Hits["cdn"]) != 1 {
+		t.Fatalf("unexpected hits: %+v", s.Hits)
+	}
+}
+
+func TestNilStats(t *testing.T) {
+	// 未配置统计时各模块传入 nil, 记录方法不做任何操作
+	var stats *Stats
+	stats.RecordTarget("Domain")
+	stats.RecordDNSQuery("8.8.8.8", nil)
+	stats.RecordEDNSQuery("Beijing", nil)
+	stats.RecordIPLookup(nil)
+	stats.RecordHit("cdn", "cloudflare")
+	stats.RecordDone()
+}
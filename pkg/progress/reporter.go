@@ -0,0 +1,109 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/winezer0/cdninfo/pkg/fileutils"
+)
+
+// Reporter 定时在终端刷新一行运行状态
+type Reporter struct {
+	stats    *Stats
+	writer   io.Writer
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// StartReporter 启动状态行刷新，interval 为刷新间隔
+func StartReporter(stats *Stats, writer io.Writer, interval time.Duration) *Reporter {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	r := &Reporter{
+		stats:    stats,
+		writer:   writer,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go r.loop()
+	return r
+}
+
+func (r *Reporter) loop() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			fmt.Fprintf(r.writer, "\r\033[K%s", StatusLine(r.stats.Snapshot()))
+		case <-r.stop:
+			// 清除状态行，避免与后续输出混在一起
+			fmt.Fprint(r.writer, "\r\033[K")
+			return
+		}
+	}
+}
+
+// Stop 停止刷新并清除状态行
+func (r *Reporter) Stop() {
+	close(r.stop)
+	<-r.done
+}
+
+// StatusLine 生成单行运行状态
+func StatusLine(s Snapshot) string {
+	dns := TotalQueries(s.DNSQueries)
+	edns := TotalQueries(s.EDNSQueries)
+	return fmt.Sprintf("[%s] targets %d done %d skip %d dup %d | dns %d fail %d timeout %d | edns %d | ipdb %d | cdn %d waf %d cloud %d",
+		time.Duration(s.ElapsedSeconds*float64(time.Second)).Truncate(time.Second),
+		s.TargetsRead, s.TargetsDone, s.TargetsSkipped, s.TargetsDup,
+		dns.Sent, dns.Failed, dns.TimedOut,
+		edns.Sent,
+		s.IPLookups,
+		TotalHits(s.Hits["cdn"]), TotalHits(s.Hits["waf"]), TotalHits(s.Hits["cloud"]),
+	)
+}
+
+// WriteSummary 输出运行结束时的统计摘要
+func WriteSummary(writer io.Writer, s Snapshot) {
+	var sb strings.Builder
+	sb.WriteString("==== Run Statistics ====\n")
+	sb.WriteString(fmt.Sprintf("Elapsed: %.1fs\n", s.ElapsedSeconds))
+	sb.WriteString(fmt.Sprintf("Targets: read %d, done %d, skipped %d, duplicate %d\n", s.TargetsRead, s.TargetsDone, s.TargetsSkipped, s.TargetsDup))
+	for _, category := range sortedKeys(s.Categories) {
+		sb.WriteString(fmt.Sprintf("  %s: %d\n", category, s.Categories[category]))
+	}
+
+	writeQueries(&sb, "DNS queries", s.DNSQueries)
+	writeQueries(&sb, "EDNS queries", s.EDNSQueries)
+	sb.WriteString(fmt.Sprintf("IP DB lookups: %d (failed %d)\n", s.IPLookups, s.IPLookupFailed))
+
+	for _, category := range sortedKeys(s.Hits) {
+		sb.WriteString(fmt.Sprintf("%s hits: %d\n", strings.ToUpper(category), TotalHits(s.Hits[category])))
+		for _, company := range sortedKeys(s.Hits[category]) {
+			sb.WriteString(fmt.Sprintf("  %s: %d\n", company, s.Hits[category][company]))
+		}
+	}
+	fmt.Fprint(writer, sb.String())
+}
+
+func writeQueries(sb *strings.Builder, title string, queries map[string]QueryStats) {
+	total := TotalQueries(queries)
+	sb.WriteString(fmt.Sprintf("%s: sent %d, failed %d, timed out %d\n", title, total.Sent, total.Failed, total.TimedOut))
+	for _, key := range sortedKeys(queries) {
+		q := queries[key]
+		sb.WriteString(fmt.Sprintf("  %s: sent %d, failed %d, timed out %d\n", key, q.Sent, q.Failed, q.TimedOut))
+	}
+}
+
+// WriteStatsFile 将统计快照写入 json 文件
+func WriteStatsFile(filePath string, s Snapshot) error {
+	return fileutils.WriteJson(filePath, s)
+}